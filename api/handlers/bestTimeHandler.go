@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	rankByScore = "score"
+	rankByTop   = "top"
+
+	// priorStrength is how many posts worth of the sub wide average every slot
+	// starts with, so a slot with one lucky post doesn't top the ranking.
+	priorStrength = 5.0
+	z95           = 1.96
+)
+
+var bestTimeRanks = []string{rankByScore, rankByTop}
+
+type PostingWindow struct {
+	Rank           int     `json:"rank"`
+	Day            int     `json:"day"`
+	DayName        string  `json:"day_name"`
+	StartHour      int     `json:"start_hour"`
+	EndHour        int     `json:"end_hour"`
+	Posts          int     `json:"posts"`
+	ExpectedScore  float64 `json:"expected_score"`
+	ScoreCILow     float64 `json:"score_ci_low"`
+	ScoreCIHigh    float64 `json:"score_ci_high"`
+	TopProbability float64 `json:"top_probability"`
+	TopCILow       float64 `json:"top_ci_low"`
+	TopCIHigh      float64 `json:"top_ci_high"`
+}
+
+func clamp(v, low, high float64) float64 {
+	return math.Max(low, math.Min(high, v))
+}
+
+// modelPostingWindows estimates the expected score and the chance of reaching
+// the top listing for every hour-of-week slot, shrinking each slot towards the
+// sub wide average in proportion to how few posts it has.
+func modelPostingWindows(slots []data.SlotStats) []PostingWindow {
+	var posts, tops int
+	var scoreSum, sqSum float64
+
+	for _, s := range slots {
+		n := float64(s.PostCount)
+		posts += s.PostCount
+		tops += s.TopCount
+		scoreSum += s.MeanScore * n
+		// recover the sum of squares from the slot mean and sample deviation
+		sqSum += s.StdDevScore*s.StdDevScore*math.Max(n-1, 0) + s.MeanScore*s.MeanScore*n
+	}
+
+	if posts == 0 {
+		return []PostingWindow{}
+	}
+
+	globalMean := scoreSum / float64(posts)
+	globalVar := math.Max(sqSum/float64(posts)-globalMean*globalMean, 0)
+	globalTopRate := float64(tops) / float64(posts)
+
+	windows := make([]PostingWindow, 0, len(slots))
+	for _, s := range slots {
+		n := float64(s.PostCount)
+		weight := n + priorStrength
+
+		expected := (s.MeanScore*n + globalMean*priorStrength) / weight
+
+		variance := globalVar
+		if s.PostCount > 1 {
+			variance = (s.StdDevScore*s.StdDevScore*(n-1) + globalVar*priorStrength) / (weight - 1)
+		}
+		scoreMargin := z95 * math.Sqrt(variance/weight)
+
+		topProbability := (float64(s.TopCount) + globalTopRate*priorStrength) / weight
+		topMargin := z95 * math.Sqrt(topProbability*(1-topProbability)/weight)
+
+		windows = append(windows, PostingWindow{
+			Day:            s.Day,
+			DayName:        time.Weekday(s.Day).String(),
+			StartHour:      s.Hour,
+			EndHour:        (s.Hour + 1) % 24,
+			Posts:          s.PostCount,
+			ExpectedScore:  math.Round(expected*100) / 100,
+			ScoreCILow:     math.Round(math.Max(expected-scoreMargin, 0)*100) / 100,
+			ScoreCIHigh:    math.Round((expected+scoreMargin)*100) / 100,
+			TopProbability: math.Round(topProbability*10000) / 10000,
+			TopCILow:       math.Round(clamp(topProbability-topMargin, 0, 1)*10000) / 10000,
+			TopCIHigh:      math.Round(clamp(topProbability+topMargin, 0, 1)*10000) / 10000,
+		})
+	}
+
+	return windows
+}
+
+func (h *Handlers) GetBestTimeHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+
+	loc, err := readLocation(qs, defaultTimezone)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	from, to, err := h.readDateRange(qs, loc, 180)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	rankBy := h.Utils.ReadStringQuery(qs, "rank_by", rankByScore)
+	if slices.Index(bestTimeRanks, rankBy) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid rank_by"))
+		return fmt.Errorf("invalid rank_by")
+	}
+
+	limit := h.Utils.ReadIntQuery(qs, "limit", 10)
+	if limit < 1 || limit > 168 {
+		h.Utils.BadRequest(c, fmt.Errorf("limit must be between 1 and 168"))
+		return fmt.Errorf("invalid limit")
+	}
+
+	params := data.FrequencyParams{
+		Timezone: loc.String(),
+		From:     from,
+		To:       to,
+		PostType: postType,
+	}
+
+	slots, err := h.Data.Posts.GetSlotStats(sub, params)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting slot stats %v", err)
+	}
+
+	windows := modelPostingWindows(slots)
+
+	sort.SliceStable(windows, func(i, j int) bool {
+		if rankBy == rankByTop {
+			return windows[i].TopProbability > windows[j].TopProbability
+		}
+		return windows[i].ExpectedScore > windows[j].ExpectedScore
+	})
+
+	if len(windows) > limit {
+		windows = windows[:limit]
+	}
+
+	for i := range windows {
+		windows[i].Rank = i + 1
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_best_time", sub), windows)
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"timezone": loc.String(),
+		"from":     from,
+		"to":       to,
+		"rank_by":  rankBy,
+		"windows":  windows,
+	})
+}
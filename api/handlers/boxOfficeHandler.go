@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/boxoffice"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	// boxOfficeTolerance is how far apart two claims for the same day can be
+	// before they conflict, since trackers round and report at different hours.
+	boxOfficeTolerance = 0.1
+
+	// collections get posted from the paid premieres on, and for months after
+	boxOfficeLead   = 3 * 24 * time.Hour
+	boxOfficeWindow = 180 * 24 * time.Hour
+)
+
+type BoxOfficePoint struct {
+	Day int `json:"day"`
+	// Reported is the claim of the most upvoted post, the one the sub went with.
+	Reported    float64 `json:"reported"`
+	Low         float64 `json:"low"`
+	High        float64 `json:"high"`
+	Claims      int     `json:"claims"`
+	Conflicting bool    `json:"conflicting"`
+	// Inferred is set when no post said which day it was for, and the day
+	// was taken from when the post was made.
+	Inferred bool `json:"inferred"`
+}
+
+type BoxOfficeSeries struct {
+	Region string           `json:"region"`
+	Kind   string           `json:"kind"`
+	Basis  string           `json:"basis"`
+	Points []BoxOfficePoint `json:"points"`
+}
+
+type BoxOfficeConflict struct {
+	Region string                `json:"region"`
+	Kind   string                `json:"kind"`
+	Basis  string                `json:"basis"`
+	Day    int                   `json:"day"`
+	Claims []data.BoxOfficeClaim `json:"claims"`
+}
+
+// extractBoxOffice reads the figures of the box office posts. It runs after
+// the posts are stored, and a failure only leaves their figures out.
+func (h *Handlers) extractBoxOffice(posts []data.Post) {
+	figures := make(map[string][]boxoffice.Figure)
+	for _, post := range posts {
+		if post.PostType == data.PostTypeBoxOffice {
+			figures[post.ID] = boxoffice.Extract(post.Title, post.Selftext)
+		}
+	}
+
+	if err := h.Data.BoxOffice.ReplaceFigures(figures); err != nil {
+		log.Error("Error storing box office figures: ", err)
+		return
+	}
+
+	log.Info("Box office posts: ", len(figures))
+}
+
+// ExtractBoxOffice reads the figures of every stored box office post again,
+// for when the parser or the post types change.
+func (h *Handlers) ExtractBoxOffice() error {
+	const batchSize = 500
+
+	stale, err := h.Data.BoxOffice.DeleteStaleFigures()
+	if err != nil {
+		return err
+	}
+
+	var after string
+	var seen, found int
+
+	for {
+		contents, err := h.Data.Posts.GetPostContentsAfter(data.PostTypeBoxOffice, after, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(contents) == 0 {
+			break
+		}
+
+		figures := make(map[string][]boxoffice.Figure, len(contents))
+		for _, c := range contents {
+			figures[c.ID] = boxoffice.Extract(c.Title, c.Selftext)
+			found += len(figures[c.ID])
+		}
+
+		if err := h.Data.BoxOffice.ReplaceFigures(figures); err != nil {
+			return err
+		}
+
+		seen += len(contents)
+		after = contents[len(contents)-1].ID
+	}
+
+	h.Responses.Bump()
+
+	log.Info("Box office posts: ", seen, " figures: ", found, " stale removed: ", stale)
+	return nil
+}
+
+// boxOfficeSeries charts the claims by region, kind and basis, one point per
+// day. A claim with no day is placed on the day before it was posted, as
+// collections come out the morning after, but only on days no post named.
+func boxOfficeSeries(claims []data.BoxOfficeClaim, release *time.Time) ([]BoxOfficeSeries, []BoxOfficeConflict, int) {
+	type seriesKey struct{ region, kind, basis string }
+
+	byDay := make(map[seriesKey]map[int][]data.BoxOfficeClaim)
+	undated := 0
+	for _, claim := range claims {
+		if claim.Day == 0 {
+			if release == nil {
+				undated++
+				continue
+			}
+			day := int(claim.CreatedUTC.Sub(*release).Hours() / 24)
+			if day < 1 {
+				undated++
+				continue
+			}
+			claim.Day = day
+			claim.Inferred = true
+		}
+
+		key := seriesKey{claim.Region, claim.Kind, claim.Basis}
+		if byDay[key] == nil {
+			byDay[key] = make(map[int][]data.BoxOfficeClaim)
+		}
+		byDay[key][claim.Day] = append(byDay[key][claim.Day], claim)
+	}
+
+	series := []BoxOfficeSeries{}
+	conflicts := []BoxOfficeConflict{}
+	claimCounts := make(map[seriesKey]int)
+
+	for key, days := range byDay {
+		s := BoxOfficeSeries{Region: key.region, Kind: key.kind, Basis: key.basis, Points: []BoxOfficePoint{}}
+
+		for day, dayClaims := range days {
+			var named []data.BoxOfficeClaim
+			for _, c := range dayClaims {
+				if !c.Inferred {
+					named = append(named, c)
+				}
+			}
+			if len(named) > 0 {
+				dayClaims = named
+			}
+
+			point := BoxOfficePoint{Day: day, Low: math.Inf(1), Claims: len(dayClaims), Inferred: len(named) == 0}
+			posts := make(map[string]bool)
+			best := -1
+			for _, c := range dayClaims {
+				point.Low = math.Min(point.Low, c.Crore)
+				point.High = math.Max(point.High, c.Crore)
+				posts[c.PostID] = true
+				if c.Upvotes > best {
+					point.Reported, best = c.Crore, c.Upvotes
+				}
+			}
+
+			point.Conflicting = len(posts) > 1 && point.High > point.Low*(1+boxOfficeTolerance)
+			if point.Conflicting {
+				conflicts = append(conflicts, BoxOfficeConflict{Region: key.region, Kind: key.kind, Basis: key.basis, Day: day, Claims: dayClaims})
+			}
+
+			s.Points = append(s.Points, point)
+			claimCounts[key] += len(dayClaims)
+		}
+
+		sort.Slice(s.Points, func(i, j int) bool {
+			return s.Points[i].Day < s.Points[j].Day
+		})
+
+		series = append(series, s)
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		ki := seriesKey{series[i].Region, series[i].Kind, series[i].Basis}
+		kj := seriesKey{series[j].Region, series[j].Kind, series[j].Basis}
+		if claimCounts[ki] != claimCounts[kj] {
+			return claimCounts[ki] > claimCounts[kj]
+		}
+		return fmt.Sprint(ki) < fmt.Sprint(kj)
+	})
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Day != conflicts[j].Day {
+			return conflicts[i].Day < conflicts[j].Day
+		}
+		return conflicts[i].Region < conflicts[j].Region
+	})
+
+	return series, conflicts, undated
+}
+
+// GetBoxOfficeHandler charts the collections reported for a movie across the
+// subs and lists the days the posts disagree on.
+func (h *Handlers) GetBoxOfficeHandler(c echo.Context) error {
+	id, err := h.Utils.ReadIntParam(c, "tmdb_id")
+	if err != nil || id < 1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid tmdb_id"))
+		return fmt.Errorf("invalid tmdb_id")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+	region := h.Utils.ReadStringQuery(qs, "region", "")
+	kind := h.Utils.ReadStringQuery(qs, "kind", "")
+
+	movie, err := h.readMovie(c, id)
+	if err != nil {
+		return err
+	}
+
+	from, to := time.Time{}, time.Now().UTC()
+	if movie.ReleaseDate != nil {
+		from = movie.ReleaseDate.Add(-boxOfficeLead)
+		to = movie.ReleaseDate.Add(boxOfficeWindow)
+	}
+
+	claims, err := h.Data.BoxOffice.GetMovieClaims(subReddits, movie.Aliases, from, to, region, kind)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting box office claims %v", err)
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%d_box_office", id), claims)
+	}
+
+	series, conflicts, undated := boxOfficeSeries(claims, movie.ReleaseDate)
+
+	return c.JSON(http.StatusOK, Cake{
+		"movie":     movie,
+		"series":    series,
+		"conflicts": conflicts,
+		"claims":    len(claims),
+		"undated":   undated,
+	})
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/internal/sentiment"
+)
+
+const (
+	minBuzzMovies = 2
+	maxBuzzMovies = 4
+
+	// buzzBodyLength is how much of the body counts toward the sentiment of
+	// a post, so a pasted article doesn't drown out the title.
+	buzzBodyLength = 500
+
+	// posts scoring within this much of 0 count as neutral
+	sentimentNeutral = 0.05
+
+	maxBuzzOffset = 180
+
+	buzzAlignDate    = "date"
+	buzzAlignRelease = "release"
+)
+
+type BuzzStats struct {
+	Mentions      int     `json:"mentions"`
+	ScoreTotal    int     `json:"score_total"`
+	CommentsTotal int     `json:"comments_total"`
+	Sentiment     float64 `json:"sentiment"`
+	Positive      int     `json:"positive"`
+	Negative      int     `json:"negative"`
+
+	sentimentSum float64
+}
+
+type BuzzMovie struct {
+	Movie
+	Totals     BuzzStats      `json:"totals"`
+	Subreddits map[string]int `json:"subreddits"`
+}
+
+type BuzzPoint struct {
+	TMDBID int `json:"tmdb_id"`
+	BuzzStats
+}
+
+// BuzzDay is a day of the comparison, a date or, when the movies are lined up
+// on their release dates, the number of days from release.
+type BuzzDay struct {
+	Day    *time.Time  `json:"day,omitempty"`
+	Offset *int        `json:"offset,omitempty"`
+	Movies []BuzzPoint `json:"movies"`
+}
+
+// BuzzRow is a movie's day, for the csv and ndjson exports.
+type BuzzRow struct {
+	Day           *time.Time `json:"day"`
+	Offset        *int       `json:"offset"`
+	TMDBID        int        `json:"tmdb_id"`
+	Title         string     `json:"title"`
+	Mentions      int        `json:"mentions"`
+	ScoreTotal    int        `json:"score_total"`
+	CommentsTotal int        `json:"comments_total"`
+	Sentiment     float64    `json:"sentiment"`
+}
+
+func (s *BuzzStats) add(post data.MentionPost) {
+	body := post.Selftext
+	if utf8.RuneCountInString(body) > buzzBodyLength {
+		body = string([]rune(body)[:buzzBodyLength])
+	}
+	score := sentiment.Score(post.Title + " " + body)
+
+	s.Mentions++
+	s.ScoreTotal += post.Score
+	s.CommentsTotal += post.NumComments
+	s.sentimentSum += score
+
+	switch {
+	case score >= sentimentNeutral:
+		s.Positive++
+	case score <= -sentimentNeutral:
+		s.Negative++
+	}
+
+	s.Sentiment = math.Round(s.sentimentSum/float64(s.Mentions)*1000) / 1000
+}
+
+// readMovieIDs reads the comma separated TMDB ids of the movies to compare.
+func readMovieIDs(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid movie id %q", part)
+		}
+		if slices.Index(ids, id) == -1 {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) < minBuzzMovies || len(ids) > maxBuzzMovies {
+		return nil, fmt.Errorf("movies must have %d to %d different ids", minBuzzMovies, maxBuzzMovies)
+	}
+
+	return ids, nil
+}
+
+// GetBuzzCompareHandler compares how much the movies were talked about across
+// the subs, day by day. With align=release each movie's days are counted
+// from its release, so films released months apart line up.
+func (h *Handlers) GetBuzzCompareHandler(c echo.Context) error {
+	qs := c.QueryParams()
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	ids, err := readMovieIDs(qs.Get("movies"))
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	align := h.Utils.ReadStringQuery(qs, "align", buzzAlignDate)
+	if align != buzzAlignDate && align != buzzAlignRelease {
+		h.Utils.BadRequest(c, fmt.Errorf("align must be date or release"))
+		return fmt.Errorf("invalid align")
+	}
+
+	var from, to time.Time
+	var before, after int
+	if align == buzzAlignDate {
+		from, to, err = h.readDateRange(qs, time.UTC, 30)
+		if err != nil {
+			h.Utils.BadRequest(c, err)
+			return err
+		}
+	} else {
+		before = h.Utils.ReadIntQuery(qs, "before", 30)
+		after = h.Utils.ReadIntQuery(qs, "after", 14)
+		if before < 0 || before > maxBuzzOffset || after < 0 || after > maxBuzzOffset {
+			h.Utils.BadRequest(c, fmt.Errorf("before and after must be between 0 and %d", maxBuzzOffset))
+			return fmt.Errorf("invalid offsets")
+		}
+	}
+
+	movies := make([]*BuzzMovie, 0, len(ids))
+	for _, id := range ids {
+		m, err := h.readMovie(c, id)
+		if err != nil {
+			return err
+		}
+		movie := &BuzzMovie{Movie: *m, Subreddits: make(map[string]int)}
+
+		if align == buzzAlignRelease && movie.ReleaseDate == nil {
+			h.Utils.BadRequest(c, fmt.Errorf("movie %d has no release date", id))
+			return fmt.Errorf("no release date")
+		}
+
+		movies = append(movies, movie)
+	}
+
+	days := int(to.Sub(from).Hours() / 24)
+	if align == buzzAlignRelease {
+		days = before + after + 1
+	}
+
+	series := make([]BuzzDay, days)
+	for i := range series {
+		if align == buzzAlignDate {
+			day := from.AddDate(0, 0, i)
+			series[i].Day = &day
+		} else {
+			offset := i - before
+			series[i].Offset = &offset
+		}
+
+		series[i].Movies = make([]BuzzPoint, len(movies))
+		for j, movie := range movies {
+			series[i].Movies[j].TMDBID = movie.TMDBID
+		}
+	}
+
+	for j, movie := range movies {
+		start, end := from, to
+		if align == buzzAlignRelease {
+			start = movie.ReleaseDate.AddDate(0, 0, -before)
+			end = movie.ReleaseDate.AddDate(0, 0, after+1)
+		}
+
+		posts, err := h.Data.Posts.GetMentionPosts(subReddits, movie.Aliases, start, end)
+		if err != nil {
+			h.Utils.InternalServerError(c, err)
+			return fmt.Errorf("error getting mentions %v", err)
+		}
+
+		for _, post := range posts {
+			i := int(post.CreatedUTC.Sub(start).Hours() / 24)
+			if i < 0 || i >= days {
+				continue
+			}
+
+			series[i].Movies[j].add(post)
+			movie.Totals.add(post)
+			movie.Subreddits[post.Subreddit]++
+		}
+	}
+
+	if format != formatJSON {
+		var rows []BuzzRow
+		for _, day := range series {
+			for j, point := range day.Movies {
+				rows = append(rows, BuzzRow{
+					Day:           day.Day,
+					Offset:        day.Offset,
+					TMDBID:        point.TMDBID,
+					Title:         movies[j].Title,
+					Mentions:      point.Mentions,
+					ScoreTotal:    point.ScoreTotal,
+					CommentsTotal: point.CommentsTotal,
+					Sentiment:     point.Sentiment,
+				})
+			}
+		}
+		return writeRows(c, format, "buzz_compare", rows)
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"align":  align,
+		"movies": movies,
+		"series": series,
+	})
+}
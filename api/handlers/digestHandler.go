@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	// digestVersion is bumped whenever the shape of DigestDocument changes, so
+	// readers of the archive can tell old documents apart.
+	digestVersion = 1
+
+	digestPeriodDays    = 7
+	digestContributors  = 5
+	digestTrendingWords = 20
+	digestPolls         = 3
+	digestTierlists     = 3
+
+	digestFormatJSON     = "json"
+	digestFormatMarkdown = "markdown"
+	digestFormatHTML     = "html"
+)
+
+var digestFormats = []string{digestFormatJSON, digestFormatMarkdown, digestFormatHTML}
+
+type DigestPeak struct {
+	Day      int    `json:"day"`
+	DayName  string `json:"day_name"`
+	Hour     int    `json:"hour"`
+	Posts    int    `json:"posts"`
+	Timezone string `json:"timezone"`
+}
+
+type DigestDocument struct {
+	Version         int                `json:"version"`
+	Subreddit       string             `json:"subreddit"`
+	Week            string             `json:"week"`
+	PeriodStart     time.Time          `json:"period_start"`
+	PeriodEnd       time.Time          `json:"period_end"`
+	TopPosts        []data.TopPosts    `json:"top_posts"`
+	MostHated       []data.TopPosts    `json:"most_hated"`
+	TopContributors []data.TopUsers    `json:"top_contributors"`
+	TrendingWords   []data.TermCount   `json:"trending_words"`
+	Peak            *DigestPeak        `json:"peak,omitempty"`
+	Polls           []data.ActivePoll  `json:"polls"`
+	Tierlists       []data.NewTierlist `json:"tierlists"`
+}
+
+// isoWeek labels a period by the ISO week it starts in, e.g. 2024-W07.
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// BuildWeeklyDigests compiles the digest of the week that just ended for every sub.
+func (h *Handlers) BuildWeeklyDigests() error {
+	end := time.Now()
+	start := end.AddDate(0, 0, -digestPeriodDays)
+
+	var failed []string
+
+	for _, sub := range subReddits {
+		if err := h.buildDigest(sub, start, end); err != nil {
+			log.Error("Error building digest for ", sub, ": ", err)
+			failed = append(failed, sub)
+		}
+	}
+
+	h.Responses.Bump()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("digests failed for %v", failed)
+	}
+
+	return nil
+}
+
+func (h *Handlers) buildDigest(sub string, start, end time.Time) error {
+	doc := DigestDocument{
+		Version:     digestVersion,
+		Subreddit:   sub,
+		Week:        isoWeek(start),
+		PeriodStart: start.UTC(),
+		PeriodEnd:   end.UTC(),
+	}
+
+	var err error
+
+	doc.TopPosts, err = h.Data.Posts.GetTopPosts(sub, categoryTop, "", digestPeriodDays, h.Scoring[data.CategoryScoring(categoryTop)], false)
+	if err != nil {
+		return err
+	}
+
+	doc.MostHated, err = h.Data.Posts.GetTopPosts(sub, categoryHated, "", digestPeriodDays, h.Scoring[data.CategoryScoring(categoryHated)], false)
+	if err != nil {
+		return err
+	}
+
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     digestContributors,
+		Sort:         "impact",
+		SortSafelist: userRankings,
+	}
+
+	doc.TopContributors, _, err = h.Data.Posts.GetTopUser(sub, categoryTop, "", digestPeriodDays, filters)
+	if err != nil {
+		return err
+	}
+
+	doc.TrendingWords, err = h.Data.Posts.GetTrendingTerms(sub, "", start, end, digestTrendingWords)
+	if err != nil {
+		return err
+	}
+
+	frequency, err := h.Data.Posts.GetPostFrequency(sub, data.FrequencyParams{
+		Timezone: defaultTimezone,
+		From:     start,
+		To:       end,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range frequency {
+		if doc.Peak == nil || f.Count > doc.Peak.Posts {
+			doc.Peak = &DigestPeak{
+				Day:      f.Day,
+				DayName:  time.Weekday(f.Day).String(),
+				Hour:     f.Hour,
+				Posts:    f.Count,
+				Timezone: defaultTimezone,
+			}
+		}
+	}
+
+	doc.Polls, err = h.Data.Digests.GetMostActivePolls(sub, start, end, digestPolls)
+	if err != nil {
+		return err
+	}
+
+	doc.Tierlists, err = h.Data.Digests.GetNewTierlists(sub, start, end, digestTierlists)
+	if err != nil {
+		return err
+	}
+
+	if doc.TopPosts == nil {
+		doc.TopPosts = []data.TopPosts{}
+	}
+	if doc.MostHated == nil {
+		doc.MostHated = []data.TopPosts{}
+	}
+	if doc.TopContributors == nil {
+		doc.TopContributors = []data.TopUsers{}
+	}
+
+	document, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error marshalling digest %v", err)
+	}
+
+	var markdown, html bytes.Buffer
+
+	if err := digestMarkdown.Execute(&markdown, doc); err != nil {
+		return fmt.Errorf("error rendering digest markdown %v", err)
+	}
+
+	if err := digestHTML.Execute(&html, doc); err != nil {
+		return fmt.Errorf("error rendering digest html %v", err)
+	}
+
+	return h.Data.Digests.UpsertDigest(data.Digest{
+		Subreddit:   sub,
+		Week:        doc.Week,
+		PeriodStart: doc.PeriodStart,
+		PeriodEnd:   doc.PeriodEnd,
+		Version:     doc.Version,
+		Document:    document,
+		Markdown:    markdown.String(),
+		HTML:        html.String(),
+	})
+}
+
+func (h *Handlers) GetDigestHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format := h.Utils.ReadStringQuery(c.QueryParams(), "format", digestFormatJSON)
+	if slices.Index(digestFormats, format) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid format"))
+		return fmt.Errorf("invalid format")
+	}
+
+	var digest *data.Digest
+
+	if week := c.Param("week"); week != "" {
+		digest, err = h.Data.Digests.GetDigestByWeek(sub, week)
+	} else {
+		digest, err = h.Data.Digests.GetLatestDigest(sub)
+	}
+
+	if err != nil {
+		if errors.Is(err, data.ErrDigestNotFound) {
+			h.Utils.NotFoundResponse(c)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting digest %v", err)
+	}
+
+	switch format {
+	case digestFormatMarkdown:
+		return c.Blob(http.StatusOK, "text/markdown; charset=UTF-8", []byte(digest.Markdown))
+	case digestFormatHTML:
+		return c.HTML(http.StatusOK, digest.HTML)
+	}
+
+	return c.JSON(http.StatusOK, Cake{"digest": digest})
+}
+
+func (h *Handlers) GetDigestArchiveHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	filters := data.Filters{}
+
+	qs := c.Request().URL.Query()
+	filters.Page = h.Utils.ReadIntQuery(qs, "page", 1)
+	filters.PageSize = h.Utils.ReadIntQuery(qs, "page_size", 10)
+
+	if err = h.Validate.Struct(filters); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	digests, metadata, err := h.Data.Digests.GetDigestArchive(sub, filters)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting digest archive %v", err)
+	}
+
+	return c.JSON(http.StatusOK, Cake{"digests": digests, "metadata": metadata})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// markdownEscaper backslash escapes the characters that would turn a post
+// title into markdown formatting.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+var digestFuncs = map[string]any{
+	"date": func(t interface{ Format(string) string }) string {
+		return t.Format("2 Jan 2006")
+	},
+	"inc": func(i int) int {
+		return i + 1
+	},
+	"mul100": func(f float64) float64 {
+		return f * 100
+	},
+}
+
+var digestMarkdown = template.Must(template.New("digest.md").Funcs(digestFuncs).Funcs(template.FuncMap{
+	"md": markdownEscaper.Replace,
+}).Parse(`# r/{{.Subreddit}} weekly digest, {{.Week}}
+
+{{date .PeriodStart}} to {{date .PeriodEnd}}
+
+## Top posts
+{{range $i, $p := .TopPosts}}
+{{inc $i}}. [{{md $p.Title}}](https://www.reddit.com{{$p.URL}}) by u/{{md $p.Author}}, {{$p.Upvotes}} upvotes, {{$p.NumComments}} comments
+{{- else}}
+No top posts this week.
+{{- end}}
+
+## Most hated
+{{range $i, $p := .MostHated}}
+{{inc $i}}. [{{md $p.Title}}](https://www.reddit.com{{$p.URL}}) by u/{{md $p.Author}}, {{printf "%.0f" (mul100 $p.UpvoteRatio)}}% upvoted
+{{- else}}
+Nothing was hated this week.
+{{- end}}
+
+## Top contributors
+{{range $i, $u := .TopContributors}}
+{{inc $i}}. u/{{md $u.User}}, {{$u.PostCount}} posts, {{$u.TotalScore}} upvotes
+{{- else}}
+No contributors this week.
+{{- end}}
+
+## Trending words
+
+{{range $i, $w := .TrendingWords}}{{if $i}}, {{end}}{{md $w.Term}} ({{$w.Count}}){{else}}No trending words this week.{{end}}
+{{with .Peak}}
+## Busiest hour
+
+{{.DayName}} at {{printf "%02d:00" .Hour}} ({{.Timezone}}), {{.Posts}} posts
+{{end}}
+## Polls
+{{range $i, $p := .Polls}}
+{{inc $i}}. {{md $p.Title}}, {{$p.PeriodVotes}} votes this week
+{{- else}}
+No polls were voted on this week.
+{{- end}}
+
+## New tierlists
+{{range $i, $t := .Tierlists}}
+{{inc $i}}. {{md $t.Title}} by u/{{md $t.Username}}
+{{- else}}
+No tierlists were made this week.
+{{- end}}
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(digestFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>r/{{.Subreddit}} weekly digest, {{.Week}}</title>
+</head>
+<body>
+<h1>r/{{.Subreddit}} weekly digest, {{.Week}}</h1>
+<p>{{date .PeriodStart}} to {{date .PeriodEnd}}</p>
+
+<h2>Top posts</h2>
+{{if .TopPosts}}<ol>
+{{range .TopPosts}}<li><a href="https://www.reddit.com{{.URL}}">{{.Title}}</a> by u/{{.Author}}, {{.Upvotes}} upvotes, {{.NumComments}} comments</li>
+{{end}}</ol>{{else}}<p>No top posts this week.</p>{{end}}
+
+<h2>Most hated</h2>
+{{if .MostHated}}<ol>
+{{range .MostHated}}<li><a href="https://www.reddit.com{{.URL}}">{{.Title}}</a> by u/{{.Author}}, {{printf "%.0f" (mul100 .UpvoteRatio)}}% upvoted</li>
+{{end}}</ol>{{else}}<p>Nothing was hated this week.</p>{{end}}
+
+<h2>Top contributors</h2>
+{{if .TopContributors}}<ol>
+{{range .TopContributors}}<li>u/{{.User}}, {{.PostCount}} posts, {{.TotalScore}} upvotes</li>
+{{end}}</ol>{{else}}<p>No contributors this week.</p>{{end}}
+
+<h2>Trending words</h2>
+{{if .TrendingWords}}<p>{{range $i, $w := .TrendingWords}}{{if $i}}, {{end}}{{$w.Term}} ({{$w.Count}}){{end}}</p>{{else}}<p>No trending words this week.</p>{{end}}
+{{with .Peak}}
+<h2>Busiest hour</h2>
+<p>{{.DayName}} at {{printf "%02d:00" .Hour}} ({{.Timezone}}), {{.Posts}} posts</p>
+{{end}}
+<h2>Polls</h2>
+{{if .Polls}}<ol>
+{{range .Polls}}<li>{{.Title}}, {{.PeriodVotes}} votes this week</li>
+{{end}}</ol>{{else}}<p>No polls were voted on this week.</p>{{end}}
+
+<h2>New tierlists</h2>
+{{if .Tierlists}}<ol>
+{{range .Tierlists}}<li>{{.Title}} by u/{{.Username}}</li>
+{{end}}</ol>{{else}}<p>No tierlists were made this week.</p>{{end}}
+</body>
+</html>
+`))
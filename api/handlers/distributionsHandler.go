@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	metricScore    = "score"
+	metricRatio    = "upvote_ratio"
+	metricComments = "num_comments"
+
+	// ratioBuckets splits the upvote ratio into steps of 0.05.
+	ratioBuckets = 20
+)
+
+var (
+	distributionMetrics = []string{metricScore, metricRatio, metricComments}
+
+	// the edges are roughly logarithmic, as both scores and comments have a long tail
+	scoreEdges   = []int{0, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	commentEdges = []int{0, 5, 10, 25, 50, 100, 250, 500, 1000, 2500}
+)
+
+type HistogramBin struct {
+	Low   *float64 `json:"low"`
+	High  *float64 `json:"high"`
+	Count int      `json:"count"`
+}
+
+type MetricDistribution struct {
+	P50       float64        `json:"p50"`
+	P90       float64        `json:"p90"`
+	P99       float64        `json:"p99"`
+	Histogram []HistogramBin `json:"histogram"`
+}
+
+type DistributionGroup struct {
+	Category string                        `json:"category"`
+	Flair    *string                       `json:"flair,omitempty"`
+	Posts    int                           `json:"posts"`
+	Metrics  map[string]MetricDistribution `json:"metrics"`
+}
+
+// DistributionRow is one histogram bin of a group, for the csv and ndjson exports.
+type DistributionRow struct {
+	Category string   `json:"category"`
+	Flair    string   `json:"flair"`
+	Metric   string   `json:"metric"`
+	Posts    int      `json:"posts"`
+	P50      float64  `json:"p50"`
+	P90      float64  `json:"p90"`
+	P99      float64  `json:"p99"`
+	Low      *float64 `json:"low"`
+	High     *float64 `json:"high"`
+	Count    int      `json:"count"`
+}
+
+// edgeBins are the bins width_bucket numbers against an array of edges: 0 is
+// below the first edge and len(edges) is the last edge and up. A missing low
+// or high is unbounded.
+func edgeBins(edges []int) []HistogramBin {
+	bins := make([]HistogramBin, len(edges)+1)
+	for i := range bins {
+		if i > 0 {
+			low := float64(edges[i-1])
+			bins[i].Low = &low
+		}
+		if i < len(edges) {
+			high := float64(edges[i])
+			bins[i].High = &high
+		}
+	}
+	return bins
+}
+
+// ratioBins are the equal steps between 0 and 1, numbered from 1 like
+// width_bucket does. The last one takes in posts with a ratio of exactly 1.
+func ratioBins(buckets int) []HistogramBin {
+	bins := make([]HistogramBin, buckets)
+	for i := range bins {
+		low := math.Round(float64(i)/float64(buckets)*1000) / 1000
+		high := math.Round(float64(i+1)/float64(buckets)*1000) / 1000
+		bins[i] = HistogramBin{Low: &low, High: &high}
+	}
+	return bins
+}
+
+func percentiles(values []float64) (float64, float64, float64) {
+	if len(values) != 3 {
+		return 0, 0, 0
+	}
+	return math.Round(values[0]*100) / 100, math.Round(values[1]*100) / 100, math.Round(values[2]*100) / 100
+}
+
+func newMetricDistribution(values []float64, bins []HistogramBin) MetricDistribution {
+	p50, p90, p99 := percentiles(values)
+	return MetricDistribution{P50: p50, P90: p90, P99: p99, Histogram: bins}
+}
+
+// buildDistributions puts the percentiles and the bucket counts of every
+// group together. Empty bins are kept so histograms of different groups and
+// ranges line up bin for bin.
+func buildDistributions(stats []data.DistributionPercentiles, counts []data.HistogramCount, byFlair bool) []DistributionGroup {
+	groups := make([]DistributionGroup, 0, len(stats))
+	index := make(map[[2]string]int, len(stats))
+
+	for _, s := range stats {
+		group := DistributionGroup{
+			Category: s.Category,
+			Posts:    s.Posts,
+			Metrics: map[string]MetricDistribution{
+				metricScore:    newMetricDistribution(s.Score, edgeBins(scoreEdges)),
+				metricRatio:    newMetricDistribution(s.Ratio, ratioBins(ratioBuckets)),
+				metricComments: newMetricDistribution(s.Comments, edgeBins(commentEdges)),
+			},
+		}
+
+		if byFlair {
+			flair := s.Flair
+			group.Flair = &flair
+		}
+
+		index[[2]string{s.Category, s.Flair}] = len(groups)
+		groups = append(groups, group)
+	}
+
+	for _, count := range counts {
+		i, ok := index[[2]string{count.Category, count.Flair}]
+		if !ok {
+			continue
+		}
+
+		bins := groups[i].Metrics[count.Metric].Histogram
+		bucket := count.Bucket
+		if count.Metric == metricRatio {
+			// the ratio buckets start at 1
+			bucket--
+		}
+
+		if bucket >= 0 && bucket < len(bins) {
+			bins[bucket].Count += count.Count
+		}
+	}
+
+	return groups
+}
+
+func distributionRows(groups []DistributionGroup) []DistributionRow {
+	var rows []DistributionRow
+	for _, group := range groups {
+		var flair string
+		if group.Flair != nil {
+			flair = *group.Flair
+		}
+
+		for _, metric := range distributionMetrics {
+			m := group.Metrics[metric]
+			for _, bin := range m.Histogram {
+				rows = append(rows, DistributionRow{
+					Category: group.Category,
+					Flair:    flair,
+					Metric:   metric,
+					Posts:    group.Posts,
+					P50:      m.P50,
+					P90:      m.P90,
+					P99:      m.P99,
+					Low:      bin.Low,
+					High:     bin.High,
+					Count:    bin.Count,
+				})
+			}
+		}
+	}
+	return rows
+}
+
+func (h *Handlers) GetDistributionsHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+
+	from, to, err := h.readDateRange(qs, time.UTC, 90)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	by := h.Utils.ReadStringQuery(qs, "by", "category")
+	if by != "category" && by != "flair" {
+		h.Utils.BadRequest(c, fmt.Errorf("by must be category or flair"))
+		return fmt.Errorf("invalid by")
+	}
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	params := data.DistributionParams{
+		From:         from,
+		To:           to,
+		ByFlair:      by == "flair",
+		ScoreEdges:   scoreEdges,
+		RatioBuckets: ratioBuckets,
+		CommentEdges: commentEdges,
+		PostType:     postType,
+	}
+
+	stats, err := h.Data.Posts.GetDistributionPercentiles(sub, params)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting distribution percentiles %v", err)
+	}
+
+	counts, err := h.Data.Posts.GetDistributionHistograms(sub, params)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting distribution histograms %v", err)
+	}
+
+	groups := buildDistributions(stats, counts, params.ByFlair)
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_distributions", sub), distributionRows(groups))
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"from":   from,
+		"to":     to,
+		"by":     by,
+		"groups": groups,
+	})
+}
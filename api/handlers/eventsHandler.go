@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	spikeBaselineDays = 28
+	spikeRecentHours  = 24
+	// Ingestion keeps around ten posts a sub a day, so an hour rarely holds
+	// more than one. Six hour buckets average two or three posts, enough for
+	// a burst to stand out from the noise.
+	spikeBucketHours = 6
+	spikeZThreshold  = 3.0
+	spikeMinPosts    = 3
+	spikeTopPosts    = 5
+	spikeTopTerms    = 10
+)
+
+func meanAndStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
+
+// zScore floors the deviation at the poisson noise of the baseline, so a sub
+// that is almost always quiet doesn't flag every single extra post as a spike.
+func zScore(value, mean, stdDev float64) float64 {
+	floor := math.Max(math.Sqrt(mean), 1)
+	return (value - mean) / math.Max(stdDev, floor)
+}
+
+func (h *Handlers) DetectActivitySpikes() error {
+	var failed []string
+
+	for _, sub := range subReddits {
+		if err := h.detectSubSpikes(sub); err != nil {
+			log.Error("Error detecting spikes for ", sub, ": ", err)
+			failed = append(failed, sub)
+		}
+	}
+
+	// even a partial run may have stored new events
+	h.Responses.Bump()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("spike detection failed for %v", failed)
+	}
+
+	return nil
+}
+
+func (h *Handlers) detectSubSpikes(sub string) error {
+	activity, err := h.Data.Events.GetActivity(sub, spikeBaselineDays+1, spikeBucketHours)
+	if err != nil {
+		return err
+	}
+
+	recentBuckets := spikeRecentHours / spikeBucketHours
+	if len(activity) <= recentBuckets {
+		return nil
+	}
+
+	split := len(activity) - recentBuckets
+	baseline, recent := activity[:split], activity[split:]
+
+	counts := make([]float64, len(baseline))
+	scores := make([]float64, len(baseline))
+	for i, bucket := range baseline {
+		counts[i] = float64(bucket.PostCount)
+		// scores are heavy tailed, compare them on a log scale
+		scores[i] = math.Log1p(math.Max(float64(bucket.ScoreTotal), 0))
+	}
+
+	countMean, countStdDev := meanAndStdDev(counts)
+	scoreMean, scoreStdDev := meanAndStdDev(scores)
+
+	for _, bucket := range recent {
+		if bucket.PostCount < spikeMinPosts {
+			continue
+		}
+
+		countZ := zScore(float64(bucket.PostCount), countMean, countStdDev)
+		scoreZ := (math.Log1p(math.Max(float64(bucket.ScoreTotal), 0)) - scoreMean) / math.Max(scoreStdDev, 0.5)
+
+		if countZ < spikeZThreshold && scoreZ < spikeZThreshold {
+			continue
+		}
+
+		end := bucket.Bucket.Add(spikeBucketHours * time.Hour)
+
+		topPosts, err := h.Data.Events.GetTopPostsInBucket(sub, bucket.Bucket, end, spikeTopPosts)
+		if err != nil {
+			return err
+		}
+
+		terms, err := h.Data.Posts.GetTrendingTerms(sub, "", bucket.Bucket, end, spikeTopTerms)
+		if err != nil {
+			return err
+		}
+
+		event := data.ActivityEvent{
+			Subreddit:     sub,
+			BucketStart:   bucket.Bucket,
+			PostCount:     bucket.PostCount,
+			ScoreTotal:    bucket.ScoreTotal,
+			BaselineCount: countMean,
+			BaselineScore: math.Expm1(scoreMean),
+			CountZ:        countZ,
+			ScoreZ:        scoreZ,
+			TopPosts:      topPosts,
+			TrendingTerms: terms,
+		}
+
+		if err := h.Data.Events.UpsertEvent(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *Handlers) GetEventsHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.Request().URL.Query()
+
+	// events are detected from all of the sub's posts as they come in, so
+	// they can't be told apart by type
+	if qs.Has("type") {
+		h.Utils.BadRequest(c, fmt.Errorf("events can't be filtered by type"))
+		return fmt.Errorf("invalid type")
+	}
+
+	filters := data.Filters{}
+
+	filters.Page = h.Utils.ReadIntQuery(qs, "page", 1)
+	filters.PageSize = h.Utils.ReadIntQuery(qs, "page_size", 10)
+
+	if err = h.Validate.Struct(filters); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	events, metadata, err := h.Data.Events.GetEvents(sub, filters)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting events %v", err)
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_events", sub), events)
+	}
+
+	if len(events) < 1 {
+		return c.JSON(http.StatusOK, Cake{"events": []data.ActivityEvent{}, "metadata": metadata})
+	}
+
+	return c.JSON(http.StatusOK, Cake{"events": events, "metadata": metadata})
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	mimeCSV    = "text/csv"
+	mimeNDJSON = "application/x-ndjson"
+
+	// exportFlushEvery is how many rows go out before the writer is flushed
+	// to the client.
+	exportFlushEvery = 100
+)
+
+var exportFormats = []string{formatJSON, formatCSV, formatNDJSON}
+
+// ExportFormat picks the representation of an analytics response. The format
+// query parameter wins over the Accept header, and json is the default.
+func ExportFormat(c echo.Context) string {
+	if format := c.QueryParam("format"); format != "" {
+		return format
+	}
+
+	for _, accept := range strings.Split(c.Request().Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case mimeCSV:
+			return formatCSV
+		case mimeNDJSON:
+			return formatNDJSON
+		}
+	}
+
+	return formatJSON
+}
+
+func (h *Handlers) readExportFormat(c echo.Context) (string, error) {
+	format := ExportFormat(c)
+	if slices.Index(exportFormats, format) == -1 {
+		err := fmt.Errorf("invalid format")
+		h.Utils.BadRequest(c, err)
+		return "", err
+	}
+
+	return format, nil
+}
+
+type exportColumn struct {
+	name  string
+	index []int
+}
+
+// exportColumns names the columns of a row after the json tags of its struct,
+// so the csv headers and ndjson keys match the json responses.
+func exportColumns(t reflect.Type) []exportColumn {
+	var columns []exportColumn
+
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		columns = append(columns, exportColumn{name: name, index: field.Index})
+	}
+
+	return columns
+}
+
+// exportCell flattens a field into a csv cell. Anything that isn't a plain
+// scalar goes in as its json.
+func exportCell(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// rowWriter streams rows of one struct type to the client as csv or ndjson,
+// flushing as it goes so nothing is held in memory.
+type rowWriter struct {
+	res     *echo.Response
+	columns []exportColumn
+	csv     *csv.Writer
+	json    *json.Encoder
+	rows    int
+}
+
+func newRowWriter(c echo.Context, format, filename string, row reflect.Type) (*rowWriter, error) {
+	res := c.Response()
+
+	w := &rowWriter{
+		res:     res,
+		columns: exportColumns(row),
+	}
+
+	switch format {
+	case formatCSV:
+		res.Header().Set(echo.HeaderContentType, mimeCSV+"; charset=UTF-8")
+		w.csv = csv.NewWriter(res)
+	case formatNDJSON:
+		res.Header().Set(echo.HeaderContentType, mimeNDJSON)
+		w.json = json.NewEncoder(res)
+	default:
+		return nil, fmt.Errorf("invalid export format %s", format)
+	}
+
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	res.WriteHeader(http.StatusOK)
+
+	if w.csv != nil {
+		header := make([]string, len(w.columns))
+		for i, column := range w.columns {
+			header[i] = column.name
+		}
+
+		if err := w.csv.Write(header); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+func (w *rowWriter) Write(row any) error {
+	v := reflect.Indirect(reflect.ValueOf(row))
+
+	if w.csv != nil {
+		record := make([]string, len(w.columns))
+		for i, column := range w.columns {
+			cell, err := exportCell(v.FieldByIndex(column.index))
+			if err != nil {
+				return err
+			}
+			record[i] = cell
+		}
+
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+	} else {
+		// go through the struct's own json so the keys match the json responses
+		if err := w.json.Encode(v.Interface()); err != nil {
+			return err
+		}
+	}
+
+	w.rows++
+	if w.rows%exportFlushEvery == 0 {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+func (w *rowWriter) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	w.res.Flush()
+	return nil
+}
+
+// writeRows streams a slice of structs in the given export format.
+func writeRows[T any](c echo.Context, format, filename string, rows []T) error {
+	w, err := newRowWriter(c, format, filename, reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// ExportPostsHandler streams the stored posts of a date range straight from
+// the database, for pulls too big for the paginated endpoints. It answers in
+// csv unless ndjson is asked for.
+func (h *Handlers) ExportPostsHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	if format == formatJSON {
+		format = formatCSV
+	}
+
+	qs := c.QueryParams()
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	from, to, err := h.readDateRange(qs, time.UTC, 30)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	// a long export is expected to outlast the server's write timeout
+	if err := http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{}); err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error lifting write deadline %v", err)
+	}
+
+	filename := fmt.Sprintf("%s_posts_%s_%s", sub, from.Format(time.DateOnly), to.AddDate(0, 0, -1).Format(time.DateOnly))
+
+	w, err := newRowWriter(c, format, filename, reflect.TypeFor[data.Post]())
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	// the status is out already, so a failure past here can only cut the body short
+	err = h.Data.Posts.StreamPosts(c.Request().Context(), sub, postType, from, to, func(post data.Post) error {
+		return w.Write(post)
+	})
+	if err != nil {
+		return fmt.Errorf("error exporting posts %v", err)
+	}
+
+	return w.Flush()
+}
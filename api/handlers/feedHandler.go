@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+const (
+	feedAtom = "atom"
+	feedRSS  = "rss"
+
+	// feedSummaryLength is how many characters of the post body go in an entry.
+	feedSummaryLength = 500
+
+	redditURL = "https://www.reddit.com"
+)
+
+var feedCategories = []string{categoryTop, categoryControversial, categoryHated}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Link      atomLink       `xml:"link"`
+	Author    atomAuthor     `xml:"author"`
+	Published string         `xml:"published"`
+	Updated   string         `xml:"updated"`
+	Category  []atomCategory `xml:"category"`
+	Summary   *atomText      `xml:"summary,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator"`
+	Category    []string `xml:"category"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// feedGUID is the short link of the post. It only depends on the post id, so
+// readers never see the same post twice however its permalink changes.
+func feedGUID(id string) string {
+	return "https://redd.it/" + id
+}
+
+func feedSummary(body string) string {
+	runes := []rune(strings.TrimSpace(body))
+	if len(runes) > feedSummaryLength {
+		return string(runes[:feedSummaryLength]) + "…"
+	}
+	return string(runes)
+}
+
+// feedUpdated is the newest post of the feed, or the zero time for an empty one.
+func feedUpdated(posts []data.TopPosts) time.Time {
+	var updated time.Time
+	for _, post := range posts {
+		if post.CreatedUTC.After(updated) {
+			updated = post.CreatedUTC
+		}
+	}
+	return updated
+}
+
+// feedBuilt is when the feed last changed, which for an empty feed is now.
+func feedBuilt(posts []data.TopPosts) time.Time {
+	if updated := feedUpdated(posts); !updated.IsZero() {
+		return updated.UTC()
+	}
+	return time.Now().UTC()
+}
+
+func buildAtomFeed(self, title, sub string, posts []data.TopPosts) atomFeed {
+	feed := atomFeed{
+		ID:      self,
+		Title:   title,
+		Updated: feedBuilt(posts).Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: fmt.Sprintf("%s/r/%s", redditURL, sub)},
+		},
+	}
+
+	for _, post := range posts {
+		entry := atomEntry{
+			ID:        feedGUID(post.ID),
+			Title:     post.Title,
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: redditURL + post.URL},
+			Author:    atomAuthor{Name: "u/" + post.Author, URI: fmt.Sprintf("%s/user/%s", redditURL, post.Author)},
+			Published: post.CreatedUTC.UTC().Format(time.RFC3339),
+			Updated:   post.CreatedUTC.UTC().Format(time.RFC3339),
+			Category:  []atomCategory{{Term: post.Category}},
+		}
+
+		if summary := feedSummary(post.Body); summary != "" {
+			entry.Summary = &atomText{Type: "text", Body: summary}
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed
+}
+
+func buildRSSFeed(self, title, sub string, posts []data.TopPosts) rssFeed {
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          fmt.Sprintf("%s/r/%s", redditURL, sub),
+			Description:   title,
+			LastBuildDate: feedBuilt(posts).Format(time.RFC1123Z),
+			AtomLink:      atomLink{Rel: "self", Type: "application/rss+xml", Href: self},
+		},
+	}
+
+	for _, post := range posts {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        redditURL + post.URL,
+			GUID:        rssGUID{IsPermaLink: false, Value: feedGUID(post.ID)},
+			PubDate:     post.CreatedUTC.UTC().Format(time.RFC1123Z),
+			Creator:     "u/" + post.Author,
+			Category:    []string{post.Category},
+			Description: feedSummary(post.Body),
+		})
+	}
+
+	return feed
+}
+
+func (h *Handlers) GetFeedHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	feed, err := h.Utils.ReadStringParam(c, "feed")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid feed %v", err)
+	}
+
+	category, kind, _ := strings.Cut(feed, ".")
+	if slices.Index(feedCategories, category) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid category"))
+		return fmt.Errorf("invalid category")
+	}
+
+	if kind != feedAtom && kind != feedRSS {
+		h.Utils.BadRequest(c, fmt.Errorf("feed must end in .atom or .rss"))
+		return fmt.Errorf("invalid feed kind")
+	}
+
+	interval := h.Utils.ReadStringQuery(c.QueryParams(), "interval", intervalWeek)
+	if slices.Index(intervals, interval) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid interval"))
+		return fmt.Errorf("invalid interval")
+	}
+
+	postType, err := h.readPostType(c.QueryParams())
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	collapse, err := h.readBoolQuery(c.QueryParams(), "collapse", false)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	scoring, err := h.scoringProfile(category, h.Utils.ReadStringQuery(c.QueryParams(), "scoring", ""))
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	// scores change the order of the feed without a newer post, so it was
+	// last modified when the posts last came in. It is read before the posts,
+	// so a feed can only look older than it is.
+	modified := h.Responses.Modified()
+
+	posts, err := h.Data.Posts.GetTopPosts(sub, category, postType, intervalDays(interval), scoring, collapse)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting top posts %v", err)
+	}
+
+	self := h.Config.PublicURL + c.Request().URL.RequestURI()
+	title := fmt.Sprintf("r/%s %s posts of the %s", sub, strings.ReplaceAll(category, "_", " "), interval)
+
+	c.Response().Header().Set(echo.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+
+	var body any
+	var contentType string
+
+	if kind == feedAtom {
+		body = buildAtomFeed(self, title, sub, posts)
+		contentType = "application/atom+xml; charset=UTF-8"
+	} else {
+		body = buildRSSFeed(self, title, sub, posts)
+		contentType = "application/rss+xml; charset=UTF-8"
+	}
+
+	out, err := xml.MarshalIndent(body, "", "  ")
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error encoding feed %v", err)
+	}
+
+	return c.Blob(http.StatusOK, contentType, append([]byte(xml.Header), out...))
+}
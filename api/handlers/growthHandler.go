@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+)
+
+type GrowthPoint struct {
+	Subreddit     string    `json:"subreddit"`
+	Day           time.Time `json:"day"`
+	Subscribers   int       `json:"subscribers"`
+	ActiveUsers   *int      `json:"active_users"`
+	PostsIngested int       `json:"posts_ingested"`
+	// Delta is the change since the snapshot before, which is nil for the first.
+	Delta *int `json:"delta"`
+}
+
+type WeeklyGrowth struct {
+	WeekStart   time.Time `json:"week_start"`
+	Subscribers int       `json:"subscribers"`
+	Delta       *int      `json:"delta"`
+	DeltaPct    *float64  `json:"delta_pct"`
+}
+
+type SubredditGrowth struct {
+	Subreddit string         `json:"subreddit"`
+	Days      []GrowthPoint  `json:"days"`
+	Weeks     []WeeklyGrowth `json:"weeks"`
+}
+
+// weekStart is the monday of the week of day.
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// buildGrowth turns the snapshots of one sub, ordered by day, into its curve.
+// A week counts with its last snapshot, so a week with a missed run still
+// compares with the one before it.
+func buildGrowth(sub string, stats []data.SubredditStat) SubredditGrowth {
+	growth := SubredditGrowth{
+		Subreddit: sub,
+		Days:      make([]GrowthPoint, 0, len(stats)),
+		Weeks:     []WeeklyGrowth{},
+	}
+
+	for i, stat := range stats {
+		point := GrowthPoint{
+			Subreddit:     sub,
+			Day:           stat.Day,
+			Subscribers:   stat.Subscribers,
+			ActiveUsers:   stat.ActiveUsers,
+			PostsIngested: stat.PostsIngested,
+		}
+
+		if i > 0 {
+			delta := stat.Subscribers - stats[i-1].Subscribers
+			point.Delta = &delta
+		}
+		growth.Days = append(growth.Days, point)
+
+		week := weekStart(stat.Day)
+		if n := len(growth.Weeks); n > 0 && growth.Weeks[n-1].WeekStart.Equal(week) {
+			growth.Weeks[n-1].Subscribers = stat.Subscribers
+			continue
+		}
+		growth.Weeks = append(growth.Weeks, WeeklyGrowth{WeekStart: week, Subscribers: stat.Subscribers})
+	}
+
+	for i := 1; i < len(growth.Weeks); i++ {
+		previous := growth.Weeks[i-1].Subscribers
+		delta := growth.Weeks[i].Subscribers - previous
+		growth.Weeks[i].Delta = &delta
+
+		if previous > 0 {
+			pct := math.Round(float64(delta)/float64(previous)*10000) / 100
+			growth.Weeks[i].DeltaPct = &pct
+		}
+	}
+
+	return growth
+}
+
+// GetGrowthHandler returns the subscriber curve of a sub, or of several to
+// compare them, given as a comma separated list.
+func (h *Handlers) GetGrowthHandler(c echo.Context) error {
+	param, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	var subs []string
+	for _, sub := range strings.Split(param, ",") {
+		sub = strings.TrimSpace(sub)
+		if slices.Index(subReddits, sub) == -1 {
+			h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+			return fmt.Errorf("invalid sub")
+		}
+		if slices.Index(subs, sub) == -1 {
+			subs = append(subs, sub)
+		}
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	from, to, err := h.readDateRange(c.QueryParams(), time.UTC, 90)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	stats, err := h.Data.Stats.GetSubredditStats(subs, from, to)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting subreddit stats %v", err)
+	}
+
+	bySub := make(map[string][]data.SubredditStat, len(subs))
+	for _, stat := range stats {
+		bySub[stat.Subreddit] = append(bySub[stat.Subreddit], stat)
+	}
+
+	growth := make([]SubredditGrowth, 0, len(subs))
+	for _, sub := range subs {
+		growth = append(growth, buildGrowth(sub, bySub[sub]))
+	}
+
+	if format != formatJSON {
+		var points []GrowthPoint
+		for _, g := range growth {
+			points = append(points, g.Days...)
+		}
+		return writeRows(c, format, fmt.Sprintf("%s_growth", strings.Join(subs, "_")), points)
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"from":   from,
+		"to":     to,
+		"growth": growth,
+	})
+}
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"slices"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,40 +21,66 @@ import (
 
 var excludedWords []string = []string{"movie", "movies", "watch", "film", "time", "films", "like", "watching", "good", "seen", "watched", "best", "better", "love", "loved", "https", "http", "webp", "png", "scene", "scenes", "song", "songs", "post", "posts", "guy", "guys", "people", "tamil", "telugu", "hindi", "malayalam", "kollywood", "bollywood", "mollywood", "tollywood", "music", "story", "actor", "actors", "youtube", "cinema", "release", "youtu", "instagram", "kinda", "share", "character", "characters", "video", "screen", "content", "version", "industry", "reddit", "called", "tells", "feel", "acting"}
 
+// intervalDays turns one of the intervals into the number of days it covers.
+func intervalDays(interval string) int {
+	switch interval {
+	case intervalWeek:
+		return 7
+	case intervalMonth:
+		return 30
+	case interval6Months:
+		return 180
+	default:
+		return 365
+	}
+}
+
+// maxTermLength keeps urls and other run-on junk out of the terms.
+const maxTermLength = 64
+
 type WordCount struct {
 	Word  string
 	Count int
 }
 
-func (h *Handlers) getMostUsedWords(texts []string, limit int) ([]WordCount, error) {
-	wordCounts := make(map[string]int)
-
-	for _, text := range texts {
-		cleanText := h.Stopword.ClearStringByLang(strings.ToLower(text), "en")
+// tokenize lowercases text and drops stopwords, short words and the words
+// every post on these subs uses anyway.
+func (h *Handlers) tokenize(text string) []string {
+	cleanText := h.Stopword.ClearStringByLang(strings.ToLower(text), "en")
 
-		words := strings.Fields(cleanText)
-		for _, word := range words {
-			if len(word) > 3 {
-				if slices.Index(excludedWords, word) == -1 {
-					wordCounts[word]++
-				}
+	var tokens []string
+	for _, word := range strings.Fields(cleanText) {
+		if len(word) > 3 && len(word) <= maxTermLength {
+			if slices.Index(excludedWords, word) == -1 {
+				tokens = append(tokens, word)
 			}
 		}
 	}
 
-	var wordCountSlice []WordCount
-	for word, count := range wordCounts {
-		wordCountSlice = append(wordCountSlice, WordCount{word, count})
+	return tokens
+}
+
+func (h *Handlers) termCounts(text string) map[string]int {
+	terms := make(map[string]int)
+	for _, word := range h.tokenize(text) {
+		terms[word]++
 	}
+	return terms
+}
 
-	sort.Slice(wordCountSlice, func(i, j int) bool {
-		return wordCountSlice[i].Count > wordCountSlice[j].Count
-	})
+// attachTerms extracts the terms of every post so they are stored along with it.
+func (h *Handlers) attachTerms(posts []data.Post) {
+	for i := range posts {
+		posts[i].Terms = h.termCounts(posts[i].Title + " " + posts[i].Selftext)
+	}
+}
 
-	if len(wordCountSlice) > limit {
-		return wordCountSlice[:limit], nil
+func toWordCounts(terms []data.TermCount) []WordCount {
+	words := make([]WordCount, 0, len(terms))
+	for _, t := range terms {
+		words = append(words, WordCount{Word: t.Term, Count: t.Count})
 	}
-	return wordCountSlice, nil
+	return words
 }
 
 type UserType struct {
@@ -152,6 +179,133 @@ func StructurePostFrequency(postFrequency []data.PostFrequency) (map[int][]int,
 	return postFrequencyMap, nil
 }
 
+type FrequencyCell struct {
+	Count   int     `json:"count"`
+	Value   int     `json:"value"`
+	Average float64 `json:"average"`
+}
+
+// slotOccurrences counts how many times each day-of-week/hour slot has
+// occurred between from and to in the given location, used to average the
+// heatmap cells.
+func slotOccurrences(from, to time.Time, loc *time.Location) [7][24]int {
+	var occurrences [7][24]int
+
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+
+	for t := from.In(loc); t.Before(to); t = t.Add(time.Hour) {
+		occurrences[t.Weekday()][t.Hour()]++
+	}
+
+	return occurrences
+}
+
+func StructureWeightedFrequency(postFrequency []data.PostFrequency, weight string, occurrences [7][24]int) map[int][]FrequencyCell {
+	cells := make(map[int][]FrequencyCell)
+
+	for day := 0; day < 7; day++ {
+		cells[day] = make([]FrequencyCell, 24)
+	}
+
+	for _, pf := range postFrequency {
+		var value int
+
+		switch weight {
+		case weightScore:
+			value = pf.ScoreTotal
+		case weightComments:
+			value = pf.CommentsTotal
+		default:
+			value = pf.Count
+		}
+
+		cell := FrequencyCell{Count: pf.Count, Value: value}
+		if n := occurrences[pf.Day][pf.Hour]; n > 0 {
+			cell.Average = float64(value) / float64(n)
+		}
+
+		cells[pf.Day][pf.Hour] = cell
+	}
+
+	return cells
+}
+
+// readBoolQuery reads a true or false query parameter.
+func (h *Handlers) readBoolQuery(qs url.Values, key string, defaultValue bool) (bool, error) {
+	value, err := strconv.ParseBool(h.Utils.ReadStringQuery(qs, key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", key)
+	}
+
+	return value, nil
+}
+
+// readPostType reads the optional type filter. An empty type means every type.
+func (h *Handlers) readPostType(qs url.Values) (string, error) {
+	postType := h.Utils.ReadStringQuery(qs, "type", "")
+	if postType != "" && slices.Index(h.Classifier.Types(), postType) == -1 {
+		return "", fmt.Errorf("invalid type")
+	}
+
+	return postType, nil
+}
+
+// readDateRange reads the from and to dates as midnights in loc. to is
+// inclusive of the whole day and defaults to today, from defaults to
+// defaultDays before to.
+func (h *Handlers) readDateRange(qs url.Values, loc *time.Location, defaultDays int) (time.Time, time.Time, error) {
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	to := today.AddDate(0, 0, 1)
+	if qs.Get("to") != "" {
+		date, err := h.Utils.ReadDateQuery(qs, "to", today)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(0, 0, -defaultDays)
+	if qs.Get("from") != "" {
+		date, err := h.Utils.ReadDateQuery(qs, "from", from)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+
+	if to.Sub(from) > maxDateRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must not be longer than %d days", int(maxDateRange.Hours()/24))
+	}
+
+	return from, to, nil
+}
+
+func readLocation(qs url.Values, defaultTimezone string) (*time.Location, error) {
+	tz := qs.Get("tz")
+	if tz == "" {
+		tz = defaultTimezone
+	}
+
+	if tz == "Local" {
+		return nil, fmt.Errorf("invalid timezone %s", tz)
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %s", tz)
+	}
+
+	return loc, nil
+}
+
 func (h *Handlers) TimePerReq(c echo.Context) error {
 	timeNow := time.Now()
 
@@ -205,6 +359,7 @@ func (h *Handlers) ScaleData(c echo.Context) error {
 
 		}
 		log.Info(i, "th iteration with ", len(allPosts), " posts")
+		h.attachTerms(allPosts)
 		after = resp.After
 		log.Info("Inserting into db ", len(allPosts))
 
@@ -263,6 +418,17 @@ func (h *Handlers) GetTrendingWordsHandlerWeb(c echo.Context) error {
 		return fmt.Errorf("invalid sub")
 	}
 
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	postType, err := h.readPostType(c.QueryParams())
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
 	interval := h.Utils.ReadStringQuery(c.QueryParams(), "interval", intervalMonth)
 
 	if slices.Index(intervals, interval) == -1 {
@@ -278,16 +444,18 @@ func (h *Handlers) GetTrendingWordsHandlerWeb(c echo.Context) error {
 		intervalInt = 30
 	}
 
-	allWords, err := h.Data.Posts.GetTrendingWords(sub, intervalInt)
+	now := time.Now()
+
+	terms, err := h.Data.Posts.GetTrendingTerms(sub, postType, now.AddDate(0, 0, -intervalInt), now, 100)
 	if err != nil {
 		h.Utils.InternalServerError(c, err)
 		return fmt.Errorf("error getting trending words %v", err)
 	}
 
-	trendingWords, err := h.getMostUsedWords(allWords, 100)
-	if err != nil {
-		h.Utils.InternalServerError(c, err)
-		return fmt.Errorf("error getting most used words %v", err)
+	trendingWords := toWordCounts(terms)
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_%s_trending_words", sub, interval), trendingWords)
 	}
 
 	return c.JSON(http.StatusOK, Cake{fmt.Sprintf("%s_%s_trending_words", sub, interval): trendingWords})
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/priyankishorems/bollytics-go/utils"
+)
+
+func TestReadDateRange(t *testing.T) {
+	h := &Handlers{Utils: utils.NewUtils()}
+
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "defaults end today",
+			query:    url.Values{},
+			wantFrom: today.AddDate(0, 0, -6),
+			wantTo:   today.AddDate(0, 0, 1),
+		},
+		{
+			name:     "to is inclusive",
+			query:    url.Values{"from": {"2024-03-01"}, "to": {"2024-03-31"}},
+			wantFrom: date(2024, time.March, 1),
+			wantTo:   date(2024, time.April, 1),
+		},
+		{
+			name:     "from defaults before to",
+			query:    url.Values{"to": {"2024-03-10"}},
+			wantFrom: date(2024, time.March, 4),
+			wantTo:   date(2024, time.March, 11),
+		},
+		{
+			name:     "single day",
+			query:    url.Values{"from": {"2024-03-10"}, "to": {"2024-03-10"}},
+			wantFrom: date(2024, time.March, 10),
+			wantTo:   date(2024, time.March, 11),
+		},
+		{
+			name:     "leap year is allowed",
+			query:    url.Values{"from": {"2024-01-01"}, "to": {"2024-12-31"}},
+			wantFrom: date(2024, time.January, 1),
+			wantTo:   date(2025, time.January, 1),
+		},
+		{
+			name:    "from after to",
+			query:   url.Values{"from": {"2024-03-11"}, "to": {"2024-03-10"}},
+			wantErr: true,
+		},
+		{
+			name:    "longer than the max range",
+			query:   url.Values{"from": {"2023-01-01"}, "to": {"2024-12-31"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid from",
+			query:   url.Values{"from": {"01-03-2024"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid to",
+			query:   url.Values{"to": {"yesterday"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := h.readDateRange(tt.query, loc, 7)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readDateRange() = %v, %v, want an error", from, to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readDateRange() error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("readDateRange() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -6,8 +6,11 @@ import (
 	tmdb "github.com/cyruzin/golang-tmdb"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/cache"
 	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/internal/minhash"
 	"github.com/priyankishorems/bollytics-go/utils"
+	"github.com/priyankishorems/bollytics-go/wordcloud"
 	sw "github.com/toadharvard/stopwords-iso"
 	graw "github.com/turnage/graw/reddit"
 	"github.com/vartanbeno/go-reddit/v2/reddit"
@@ -15,14 +18,21 @@ import (
 
 type Cake map[string]interface{}
 type Handlers struct {
-	Config    utils.Config
-	Validate  validator.Validate
-	Utils     utils.Utilities
-	Data      data.Models
-	Tmdb      *tmdb.Client
-	RedditBot graw.Bot
-	Reddit    *reddit.Client
-	Stopword  sw.StopwordsMapping
+	Config     utils.Config
+	Validate   validator.Validate
+	Utils      utils.Utilities
+	Data       data.Models
+	Tmdb       *tmdb.Client
+	RedditBot  graw.Bot
+	Reddit     *reddit.Client
+	Stopword   sw.StopwordsMapping
+	WordClouds *wordcloud.Cache
+	Responses  *cache.Cache
+	Scoring    map[string]data.ScoringProfile
+	Classifier *data.PostClassifier
+	Minhash    *minhash.Hasher
+	// MovieAliases are the extra names of movies by TMDB id.
+	MovieAliases map[int][]string
 }
 
 func (h *Handlers) HomeFunc(c echo.Context) error {
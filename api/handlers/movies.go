@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tmdb "github.com/cyruzin/golang-tmdb"
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/utils"
+)
+
+const (
+	// maxMovieAliases caps the names a movie is searched by. TMDB lists
+	// dozens of alternative titles for some films, mostly foreign ones
+	// nobody writes here.
+	maxMovieAliases = 10
+	minAliasLength  = 3
+
+	// TMDB answers an unknown or malformed movie id with one of these codes
+	// in the body of its 404. The client only keeps the body.
+	tmdbInvalidID = 6
+	tmdbNotFound  = 34
+)
+
+// Movie is a TMDB movie with the names posts call it by.
+type Movie struct {
+	TMDBID      int        `json:"tmdb_id"`
+	Title       string     `json:"title"`
+	ReleaseDate *time.Time `json:"release_date"`
+	Aliases     []string   `json:"aliases"`
+}
+
+// movieAliases lists the names the movie is searched by: its titles, the
+// configured aliases, then TMDB's alternative titles. A subtitle is dropped
+// too, since nobody writes out "Leo: Bloody Sweet" in a post.
+func movieAliases(details *tmdb.MovieDetails, configured []string) []string {
+	names := []string{details.Title, details.OriginalTitle}
+	names = append(names, configured...)
+	if details.MovieAlternativeTitlesAppend != nil && details.AlternativeTitles != nil {
+		for _, t := range details.AlternativeTitles.Titles {
+			names = append(names, t.Title)
+		}
+	}
+
+	var aliases []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if utf8.RuneCountInString(name) < minAliasLength || seen[key] || len(aliases) >= maxMovieAliases {
+			return
+		}
+		seen[key] = true
+		aliases = append(aliases, name)
+	}
+
+	for _, name := range names {
+		add(name)
+		for _, sep := range []string{":", " - ", " ("} {
+			if i := strings.Index(name, sep); i > 0 {
+				add(name[:i])
+			}
+		}
+	}
+
+	return aliases
+}
+
+func (h *Handlers) resolveMovie(id int) (*Movie, error) {
+	details, err := h.Tmdb.GetMovieDetails(id, map[string]string{"append_to_response": "alternative_titles"})
+	if err != nil {
+		return nil, err
+	}
+
+	movie := &Movie{
+		TMDBID:  id,
+		Title:   details.Title,
+		Aliases: movieAliases(details, h.MovieAliases[id]),
+	}
+
+	if details.ReleaseDate != "" {
+		release, err := time.Parse(time.DateOnly, details.ReleaseDate)
+		if err == nil {
+			movie.ReleaseDate = &release
+		}
+	}
+
+	return movie, nil
+}
+
+// readMovie resolves the movie from TMDB, answering the request itself when
+// that fails: 404 when TMDB has no such movie, 502 when TMDB couldn't answer.
+func (h *Handlers) readMovie(c echo.Context, id int) (*Movie, error) {
+	movie, err := h.resolveMovie(id)
+	if err != nil {
+		var tmdbErr tmdb.Error
+		if errors.As(err, &tmdbErr) && (tmdbErr.StatusCode == tmdbNotFound || tmdbErr.StatusCode == tmdbInvalidID) {
+			h.Utils.CustomErrorResponse(c, utils.Cake{"error": fmt.Sprintf("movie %d not found", id)}, http.StatusNotFound, err)
+			return nil, err
+		}
+		h.Utils.CustomErrorResponse(c, utils.Cake{"error": "could not fetch the movie from TMDB"}, http.StatusBadGateway, err)
+		return nil, err
+	}
+
+	return movie, nil
+}
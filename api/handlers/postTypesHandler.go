@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetPostTypesHandler breaks the posts of a range down by type, with the
+// engagement each type gets.
+func (h *Handlers) GetPostTypesHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	from, to, err := h.readDateRange(c.QueryParams(), time.UTC, 30)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	breakdown, err := h.Data.Posts.GetPostTypeBreakdown(sub, from, to)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting post type breakdown %v", err)
+	}
+
+	var total int
+	for _, s := range breakdown {
+		total += s.Posts
+	}
+
+	for i := range breakdown {
+		s := &breakdown[i]
+		s.Share = math.Round(float64(s.Posts)/float64(total)*10000) / 10000
+		s.MeanScore = math.Round(s.MeanScore*100) / 100
+		s.MedianScore = math.Round(s.MedianScore*100) / 100
+		s.MeanComments = math.Round(s.MeanComments*100) / 100
+		s.MeanUpvoteRatio = math.Round(s.MeanUpvoteRatio*10000) / 10000
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_types", sub), breakdown)
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"from":  from,
+		"to":    to,
+		"posts": total,
+		"types": breakdown,
+	})
+}
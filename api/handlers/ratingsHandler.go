@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/ratings"
+)
+
+const (
+	ratingSamples = 5
+
+	// reviews start with the premieres and overseas shows before release,
+	// and a movie stops being reviewed a while after it leaves theatres
+	reviewLead   = 14 * 24 * time.Hour
+	reviewWindow = 365 * 24 * time.Hour
+)
+
+// RatedReview is a review post with the rating read out of it.
+type RatedReview struct {
+	ID         string    `json:"id"`
+	Subreddit  string    `json:"subreddit"`
+	Title      string    `json:"title"`
+	Author     string    `json:"author"`
+	URL        string    `json:"url"`
+	Upvotes    int       `json:"upvotes"`
+	CreatedUTC time.Time `json:"created_utc"`
+	Rating     float64   `json:"rating"`
+	Raw        string    `json:"raw"`
+	Source     string    `json:"source"`
+}
+
+// CommunityRating is the movie's rating out of ratings.Scale. Weighted counts
+// every review by its upvotes, so a review the sub agreed with counts more.
+type CommunityRating struct {
+	Reviews  int      `json:"reviews"`
+	Scale    int      `json:"scale"`
+	Weighted *float64 `json:"weighted"`
+	Mean     *float64 `json:"mean"`
+	Median   *float64 `json:"median"`
+}
+
+type RatingBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+func roundRating(v float64) *float64 {
+	r := math.Round(v*100) / 100
+	return &r
+}
+
+func communityRating(reviews []RatedReview) (CommunityRating, []RatingBin) {
+	rating := CommunityRating{Reviews: len(reviews), Scale: ratings.Scale}
+
+	bins := make([]RatingBin, ratings.Scale)
+	for i := range bins {
+		bins[i] = RatingBin{From: float64(i), To: float64(i + 1)}
+	}
+
+	if len(reviews) == 0 {
+		return rating, bins
+	}
+
+	values := make([]float64, len(reviews))
+	var sum, weighted, weights float64
+	for i, review := range reviews {
+		values[i] = review.Rating
+		sum += review.Rating
+
+		// a downvoted review still says something, just not much
+		weight := float64(max(review.Upvotes, 1))
+		weighted += review.Rating * weight
+		weights += weight
+
+		bins[min(int(review.Rating), ratings.Scale-1)].Count++
+	}
+
+	sort.Float64s(values)
+	median := values[len(values)/2]
+	if len(values)%2 == 0 {
+		median = (values[len(values)/2-1] + median) / 2
+	}
+
+	rating.Weighted = roundRating(weighted / weights)
+	rating.Mean = roundRating(sum / float64(len(values)))
+	rating.Median = roundRating(median)
+
+	return rating, bins
+}
+
+// GetMovieRatingHandler rates a movie by the ratings in the review posts
+// about it across the subs.
+func (h *Handlers) GetMovieRatingHandler(c echo.Context) error {
+	id, err := h.Utils.ReadIntParam(c, "tmdb_id")
+	if err != nil || id < 1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid tmdb_id"))
+		return fmt.Errorf("invalid tmdb_id")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	movie, err := h.readMovie(c, id)
+	if err != nil {
+		return err
+	}
+
+	// without a release date there's no telling a remake's reviews apart
+	// from the original's, so every review with the title counts
+	from, to := time.Time{}, time.Now().UTC()
+	if movie.ReleaseDate != nil {
+		from = movie.ReleaseDate.Add(-reviewLead)
+		to = movie.ReleaseDate.Add(reviewWindow)
+	}
+
+	posts, err := h.Data.Posts.GetReviewPosts(subReddits, movie.Aliases, from, to)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting reviews %v", err)
+	}
+
+	reviews := []RatedReview{}
+	for _, post := range posts {
+		rating, ok := ratings.Extract(post.Title, post.Selftext)
+		if !ok {
+			continue
+		}
+
+		reviews = append(reviews, RatedReview{
+			ID:         post.ID,
+			Subreddit:  post.Subreddit,
+			Title:      post.Title,
+			Author:     post.Author,
+			URL:        post.URL,
+			Upvotes:    post.Upvotes,
+			CreatedUTC: post.CreatedUTC,
+			Rating:     rating.Value,
+			Raw:        rating.Raw,
+			Source:     rating.Source,
+		})
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%d_reviews", id), reviews)
+	}
+
+	rating, distribution := communityRating(reviews)
+
+	// the posts come highest score first
+	samples := reviews[:min(ratingSamples, len(reviews))]
+
+	return c.JSON(http.StatusOK, Cake{
+		"movie":        movie,
+		"rating":       rating,
+		"distribution": distribution,
+		"samples":      samples,
+	})
+}
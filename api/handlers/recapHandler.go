@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	tmdb "github.com/cyruzin/golang-tmdb"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/internal/logodds"
+)
+
+const (
+	// recapVersion is bumped whenever the shape of RecapDocument changes.
+	recapVersion = 1
+
+	recapMonthPosts    = 3
+	recapControversies = 5
+	recapContributors  = 10
+	recapWords         = 10
+	recapPolls         = 5
+	recapTierlists     = 5
+	recapUserPolls     = 5
+
+	// recapMovieCandidates are the year's most popular films on TMDB that
+	// get counted, recapMovies of them make the recap.
+	recapMovieCandidates = 20
+	recapMovies          = 10
+
+	// a word said this few times in the year can't be the word of the year
+	recapMinWordCount = 20
+
+	// reddit launched in 2005
+	minRecapYear = 2005
+)
+
+// subLanguages are the original languages, as TMDB lists them, of the films
+// each sub is about.
+var subLanguages = map[string]string{
+	"kollywood":       "ta",
+	"MalayalamMovies": "ml",
+	"tollywood":       "te",
+	"bollywood":       "hi",
+}
+
+type RecapMonth struct {
+	Month string           `json:"month"`
+	Posts []data.RecapPost `json:"posts"`
+}
+
+type RecapMovie struct {
+	TMDBID        int    `json:"tmdb_id"`
+	Title         string `json:"title"`
+	ReleaseDate   string `json:"release_date"`
+	Mentions      int    `json:"mentions"`
+	ScoreTotal    int    `json:"score_total"`
+	CommentsTotal int    `json:"comments_total"`
+}
+
+type RecapHour struct {
+	Hour     int    `json:"hour"`
+	Posts    int    `json:"posts"`
+	Timezone string `json:"timezone"`
+}
+
+// RecapDocument is a sub's year. The word of the year is the word the sub
+// took to most compared to the year before, and the plain most used word when
+// there is no year before to compare with. Posts are deleted after a year, so
+// the words are counted from the monthly term counts every build stores.
+type RecapDocument struct {
+	Version       int                     `json:"version"`
+	Subreddit     string                  `json:"subreddit"`
+	Year          int                     `json:"year"`
+	Complete      bool                    `json:"complete"`
+	PeriodStart   time.Time               `json:"period_start"`
+	PeriodEnd     time.Time               `json:"period_end"`
+	Totals        data.RecapTotals        `json:"totals"`
+	TopPosts      []RecapMonth            `json:"top_posts"`
+	Controversies []data.RecapPost        `json:"controversies"`
+	Contributors  []data.RecapContributor `json:"contributors"`
+	Movies        []RecapMovie            `json:"movies"`
+	WordOfTheYear *logodds.Score          `json:"word_of_the_year"`
+	Words         []logodds.Score         `json:"words"`
+	BusiestHour   *RecapHour              `json:"busiest_hour"`
+	Polls         []data.ActivePoll       `json:"polls"`
+	Tierlists     []data.NewTierlist      `json:"tierlists"`
+}
+
+// UserRecap is a user's year in the polls and surveys of every sub.
+type UserRecap struct {
+	Year            int                   `json:"year"`
+	PollsCreated    int                   `json:"polls_created"`
+	TopPolls        []data.UserRecapPoll  `json:"top_polls"`
+	Votes           int                   `json:"votes"`
+	VotesWithLeader int                   `json:"votes_with_leader"`
+	VotesBySub      []data.UserRecapVotes `json:"votes_by_sub"`
+	Surveys         data.UserRecapSurveys `json:"surveys"`
+}
+
+func yearPeriod(year int) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// BuildYearlyRecaps compiles the recap of the year the day before fell in, so
+// on new year's day the year just over gets its final recap.
+func (h *Handlers) BuildYearlyRecaps() error {
+	return h.BuildRecaps(time.Now().UTC().AddDate(0, 0, -1).Year())
+}
+
+// BuildRecaps compiles the recap of the year for every sub.
+func (h *Handlers) BuildRecaps(year int) error {
+	var failed []string
+
+	for _, sub := range subReddits {
+		if err := h.buildRecap(sub, year); err != nil {
+			log.Error("Error building recap for ", sub, ": ", err)
+			failed = append(failed, sub)
+		}
+	}
+
+	h.Responses.Bump()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("recaps failed for %v", failed)
+	}
+
+	return nil
+}
+
+func (h *Handlers) buildRecap(sub string, year int) error {
+	start, end := yearPeriod(year)
+
+	now := time.Now().UTC()
+	doc := RecapDocument{
+		Version:     recapVersion,
+		Subreddit:   sub,
+		Year:        year,
+		Complete:    !now.Before(end),
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+	if !doc.Complete {
+		doc.PeriodEnd = now
+	}
+
+	var err error
+
+	doc.Totals, err = h.Data.Recaps.GetRecapTotals(sub, start, end)
+	if err != nil {
+		return err
+	}
+
+	monthPosts, err := h.Data.Recaps.GetRecapPosts(sub, categoryTop, start, end, h.Scoring[data.CategoryScoring(categoryTop)], recapMonthPosts, true)
+	if err != nil {
+		return err
+	}
+
+	doc.TopPosts = []RecapMonth{}
+	for _, post := range monthPosts {
+		if n := len(doc.TopPosts); n == 0 || doc.TopPosts[n-1].Month != post.Month {
+			doc.TopPosts = append(doc.TopPosts, RecapMonth{Month: post.Month})
+		}
+		month := &doc.TopPosts[len(doc.TopPosts)-1]
+		month.Posts = append(month.Posts, post)
+	}
+
+	doc.Controversies, err = h.Data.Recaps.GetRecapPosts(sub, categoryControversial, start, end, h.Scoring[data.CategoryScoring(categoryControversial)], recapControversies, false)
+	if err != nil {
+		return err
+	}
+
+	doc.Contributors, err = h.Data.Recaps.GetRecapContributors(sub, start, end, recapContributors)
+	if err != nil {
+		return err
+	}
+
+	doc.Movies, err = h.recapMovies(sub, year, start, end)
+	if err != nil {
+		// the rest of the recap is still worth having without TMDB
+		log.Error("Error getting recap movies for ", sub, ": ", err)
+		doc.Movies = []RecapMovie{}
+	}
+
+	// store the term counts of every finished month whose posts are all
+	// still around, before the oldest of them are deleted
+	retained := monthStart(now.AddDate(0, 0, -data.PostRetentionDays)).AddDate(0, 1, 0)
+	if err := h.Data.Recaps.SnapshotTermMonths(sub, retained, monthStart(now)); err != nil {
+		return err
+	}
+
+	doc.WordOfTheYear, doc.Words, err = h.recapWords(sub, start, end)
+	if err != nil {
+		return err
+	}
+
+	frequency, err := h.Data.Posts.GetPostFrequency(sub, data.FrequencyParams{
+		Timezone: defaultTimezone,
+		From:     start,
+		To:       end,
+	})
+	if err != nil {
+		return err
+	}
+
+	hours := make([]int, 24)
+	for _, f := range frequency {
+		hours[f.Hour] += f.Count
+	}
+	for hour, posts := range hours {
+		if posts > 0 && (doc.BusiestHour == nil || posts > doc.BusiestHour.Posts) {
+			doc.BusiestHour = &RecapHour{Hour: hour, Posts: posts, Timezone: defaultTimezone}
+		}
+	}
+
+	doc.Polls, err = h.Data.Digests.GetMostActivePolls(sub, start, end, recapPolls)
+	if err != nil {
+		return err
+	}
+
+	// tierlists have no votes, the newest of the year stand in for the most voted
+	doc.Tierlists, err = h.Data.Digests.GetNewTierlists(sub, start, end, recapTierlists)
+	if err != nil {
+		return err
+	}
+
+	document, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error marshalling recap %v", err)
+	}
+
+	return h.Data.Recaps.UpsertRecap(data.Recap{
+		Subreddit: sub,
+		Year:      year,
+		Complete:  doc.Complete,
+		Version:   doc.Version,
+		Document:  document,
+	})
+}
+
+// recapMovies ranks the year's most popular films in the sub's language by
+// the posts of the sub that mention them.
+func (h *Handlers) recapMovies(sub string, year int, start, end time.Time) ([]RecapMovie, error) {
+	movies := []RecapMovie{}
+
+	language, ok := subLanguages[sub]
+	if !ok {
+		return movies, nil
+	}
+
+	discover, err := h.Tmdb.GetDiscoverMovie(map[string]string{
+		"with_original_language": language,
+		"primary_release_year":   strconv.Itoa(year),
+		"sort_by":                "popularity.desc",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if discover.DiscoverMovieResults == nil {
+		return movies, nil
+	}
+
+	for i, result := range discover.Results {
+		if i == recapMovieCandidates {
+			break
+		}
+
+		details := &tmdb.MovieDetails{}
+		details.Title = result.Title
+		details.OriginalTitle = result.OriginalTitle
+
+		aliases := movieAliases(details, h.MovieAliases[int(result.ID)])
+		if len(aliases) == 0 {
+			continue
+		}
+
+		mentions, err := h.Data.Recaps.GetRecapMentions(sub, aliases, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		if mentions.Posts == 0 {
+			continue
+		}
+
+		movies = append(movies, RecapMovie{
+			TMDBID:        int(result.ID),
+			Title:         result.Title,
+			ReleaseDate:   result.ReleaseDate,
+			Mentions:      mentions.Posts,
+			ScoreTotal:    mentions.ScoreTotal,
+			CommentsTotal: mentions.CommentsTotal,
+		})
+	}
+
+	sort.Slice(movies, func(i, j int) bool {
+		if movies[i].Mentions != movies[j].Mentions {
+			return movies[i].Mentions > movies[j].Mentions
+		}
+		return movies[i].CommentsTotal > movies[j].CommentsTotal
+	})
+
+	return movies[:min(recapMovies, len(movies))], nil
+}
+
+// recapWords compares the words of the year with the year before's.
+func (h *Handlers) recapWords(sub string, start, end time.Time) (*logodds.Score, []logodds.Score, error) {
+	before, year, err := h.Data.Recaps.GetRecapTermCounts(sub, start.AddDate(-1, 0, 0), start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	words := []logodds.Score{}
+	for _, s := range logodds.Compare(year, before, logodds.DefaultPrior, recapMinWordCount) {
+		if s.Z <= 0 || len(words) == recapWords {
+			break
+		}
+		words = append(words, s)
+	}
+
+	if len(words) > 0 {
+		return &words[0], words, nil
+	}
+
+	// the sub's first year, or one too quiet to compare
+	var top *logodds.Score
+	for term, count := range year {
+		if top == nil || count > top.Count || (count == top.Count && term < top.Term) {
+			top = &logodds.Score{Term: term, Count: count}
+		}
+	}
+
+	return top, words, nil
+}
+
+func (h *Handlers) readRecapYear(c echo.Context) (int, error) {
+	year, err := h.Utils.ReadIntParam(c, "year")
+	if err != nil || year < minRecapYear || year > time.Now().UTC().Year() {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid year"))
+		return 0, fmt.Errorf("invalid year")
+	}
+
+	return year, nil
+}
+
+func (h *Handlers) GetRecapHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	year, err := h.readRecapYear(c)
+	if err != nil {
+		return err
+	}
+
+	recap, err := h.Data.Recaps.GetRecap(sub, year)
+	if err != nil {
+		if errors.Is(err, data.ErrRecapNotFound) {
+			h.Utils.NotFoundResponse(c)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting recap %v", err)
+	}
+
+	return c.JSON(http.StatusOK, Cake{"recap": recap})
+}
+
+// GetUserRecapHandler recaps the logged in user's year. It is small enough to
+// put together on request.
+func (h *Handlers) GetUserRecapHandler(c echo.Context) error {
+	reddit_uid := c.Get("reddit_uid").(string)
+
+	year, err := h.readRecapYear(c)
+	if err != nil {
+		return err
+	}
+
+	start, end := yearPeriod(year)
+
+	recap := UserRecap{Year: year}
+
+	recap.TopPolls, recap.PollsCreated, err = h.Data.Recaps.GetUserRecapPolls(reddit_uid, start, end, recapUserPolls)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting user recap polls %v", err)
+	}
+
+	recap.VotesBySub, err = h.Data.Recaps.GetUserRecapVotes(reddit_uid, start, end)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting user recap votes %v", err)
+	}
+
+	for _, v := range recap.VotesBySub {
+		recap.Votes += v.Votes
+		recap.VotesWithLeader += v.WithLeader
+	}
+
+	recap.Surveys, err = h.Data.Recaps.GetUserRecapSurveys(reddit_uid, start, end)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting user recap surveys %v", err)
+	}
+
+	return c.JSON(http.StatusOK, Cake{"recap": recap})
+}
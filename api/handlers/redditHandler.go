@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
 	"github.com/priyankishorems/bollytics-go/internal/data"
 	"github.com/vartanbeno/go-reddit/v2/reddit"
 )
@@ -24,16 +27,30 @@ const (
 	categoryControversial       = "controversial"
 	categoryTopAndControversial = "top_and_controversial"
 	categoryHated               = "hated"
+	sortRelevance               = "relevance"
+	sortScore                   = "score"
+	sortRecency                 = "recency"
+	weightCount                 = "count"
+	weightScore                 = "score"
+	weightComments              = "comments"
+	defaultTimezone             = "Asia/Kolkata"
+	maxDateRange                = 366 * 24 * time.Hour
 )
 
 var intervals = []string{intervalWeek, intervalMonth, interval6Months, intervalYear}
 
+var searchSorts = []string{sortRelevance, sortScore, sortRecency}
+
+var frequencyWeights = []string{weightCount, weightScore, weightComments}
+
+var userRankings = []string{"posts", "score", "median", "impact", "consistency"}
+
 func (h *Handlers) VerifySession(c echo.Context) error {
 	reddit_id := c.Get("reddit_id").(string)
 	return c.JSON(http.StatusOK, Cake{"message": "Session verified", "reddit_id": reddit_id})
 }
 
-func (h *Handlers) GetTrendingWordsHandler(sub string, interval string) ([]WordCount, error) {
+func (h *Handlers) GetTrendingWordsHandler(sub, interval, postType string) ([]WordCount, error) {
 
 	if slices.Index(subReddits, sub) == -1 {
 		return nil, fmt.Errorf("invalid sub")
@@ -50,17 +67,14 @@ func (h *Handlers) GetTrendingWordsHandler(sub string, interval string) ([]WordC
 		intervalInt = 30
 	}
 
-	allWords, err := h.Data.Posts.GetTrendingWords(sub, intervalInt)
-	if err != nil {
-		return nil, fmt.Errorf("error getting trending words %v", err)
-	}
+	now := time.Now()
 
-	trendingWords, err := h.getMostUsedWords(allWords, 100)
+	terms, err := h.Data.Posts.GetTrendingTerms(sub, postType, now.AddDate(0, 0, -intervalInt), now, 100)
 	if err != nil {
-		return nil, fmt.Errorf("error getting most used words %v", err)
+		return nil, fmt.Errorf("error getting trending words %v", err)
 	}
 
-	return trendingWords, nil
+	return toWordCounts(terms), nil
 }
 
 func (h *Handlers) GetPostFrequencyHandler(c echo.Context) error {
@@ -75,19 +89,69 @@ func (h *Handlers) GetPostFrequencyHandler(c echo.Context) error {
 		return fmt.Errorf("invalid sub")
 	}
 
-	frequency, err := h.Data.Posts.GetPostFrequency(sub)
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+
+	loc, err := readLocation(qs, defaultTimezone)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	from, to, err := h.readDateRange(qs, loc, 28)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	weight := h.Utils.ReadStringQuery(qs, "weight", weightCount)
+	if slices.Index(frequencyWeights, weight) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid weight"))
+		return fmt.Errorf("invalid weight")
+	}
+
+	params := data.FrequencyParams{
+		Timezone: loc.String(),
+		From:     from,
+		To:       to,
+		PostType: postType,
+	}
+
+	frequency, err := h.Data.Posts.GetPostFrequency(sub, params)
 	if err != nil {
 		h.Utils.InternalServerError(c, err)
 		return fmt.Errorf("error getting post frequency %v", err)
 	}
 
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_frequency", sub), frequency)
+	}
+
 	frequencyMap, err := StructurePostFrequency(frequency)
 	if err != nil {
 		h.Utils.InternalServerError(c, err)
 		return fmt.Errorf("error structuring post frequency %v", err)
 	}
 
-	return c.JSON(http.StatusOK, Cake{fmt.Sprintf("%s_month_frequency", sub): frequencyMap})
+	heatmap := Cake{
+		"timezone": loc.String(),
+		"from":     from,
+		"to":       to,
+		"weight":   weight,
+		"cells":    StructureWeightedFrequency(frequency, weight, slotOccurrences(from, to, loc)),
+	}
+
+	return c.JSON(http.StatusOK, Cake{fmt.Sprintf("%s_month_frequency", sub): frequencyMap, "heatmap": heatmap})
 }
 
 func (h *Handlers) GetTopPostsHandler(c echo.Context) error {
@@ -103,6 +167,17 @@ func (h *Handlers) GetTopPostsHandler(c echo.Context) error {
 		return fmt.Errorf("invalid sub")
 	}
 
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	postType, err := h.readPostType(c.QueryParams())
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
 	interval := h.Utils.ReadStringQuery(c.QueryParams(), "interval", intervalMonth)
 
 	category, err := h.Utils.ReadStringParam(c, "category")
@@ -135,12 +210,28 @@ func (h *Handlers) GetTopPostsHandler(c echo.Context) error {
 		intervalInt = 365
 	}
 
-	topPosts, err := h.Data.Posts.GetTopPosts(sub, category, intervalInt)
+	scoring, err := h.scoringProfile(category, h.Utils.ReadStringQuery(c.QueryParams(), "scoring", ""))
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	collapse, err := h.readBoolQuery(c.QueryParams(), "collapse", false)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	topPosts, err := h.Data.Posts.GetTopPosts(sub, category, postType, intervalInt, scoring, collapse)
 	if err != nil {
 		h.Utils.InternalServerError(c, err)
 		return fmt.Errorf("error getting top users %v", err)
 	}
 
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_%s_posts", sub, category), topPosts)
+	}
+
 	responseLength := len(topPosts)
 	if responseLength < 1 {
 		return c.JSON(http.StatusOK, Cake{"posts": []data.TopPosts{}})
@@ -162,6 +253,17 @@ func (h *Handlers) GetTopUsersHandler(c echo.Context) error {
 		return fmt.Errorf("invalid sub")
 	}
 
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	postType, err := h.readPostType(c.QueryParams())
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
 	interval := h.Utils.ReadStringQuery(c.QueryParams(), "interval", intervalMonth)
 
 	category, err := h.Utils.ReadStringParam(c, "category")
@@ -194,18 +296,122 @@ func (h *Handlers) GetTopUsersHandler(c echo.Context) error {
 		intervalInt = 365
 	}
 
-	topUsers, err := h.Data.Posts.GetTopUser(sub, category, intervalInt)
+	qs := c.QueryParams()
+
+	filters := data.Filters{
+		Page:         h.Utils.ReadIntQuery(qs, "page", 1),
+		PageSize:     h.Utils.ReadIntQuery(qs, "page_size", 5),
+		Sort:         h.Utils.ReadStringQuery(qs, "rank", "posts"),
+		SortSafelist: userRankings,
+	}
+
+	if slices.Index(filters.SortSafelist, filters.Sort) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid rank"))
+		return fmt.Errorf("invalid rank")
+	}
+
+	if err = h.Validate.Struct(filters); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	topUsers, metadata, err := h.Data.Posts.GetTopUser(sub, category, postType, intervalInt, filters)
 	if err != nil {
 		h.Utils.InternalServerError(c, err)
 		return fmt.Errorf("error getting top users %v", err)
 	}
 
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_%s_users", sub, category), topUsers)
+	}
+
 	responseLength := len(topUsers)
 	if responseLength < 1 {
 		return c.JSON(http.StatusOK, Cake{"message": "No users found"})
 	}
 
-	return c.JSON(http.StatusOK, Cake{"users": topUsers})
+	return c.JSON(http.StatusOK, Cake{"users": topUsers, "metadata": metadata})
+}
+
+func (h *Handlers) SearchPostsHandler(c echo.Context) error {
+	qs := c.QueryParams()
+
+	q := strings.TrimSpace(h.Utils.ReadStringQuery(qs, "q", ""))
+	if q == "" {
+		h.Utils.BadRequest(c, fmt.Errorf("search query is required"))
+		return fmt.Errorf("search query is required")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	sub := h.Utils.ReadStringQuery(qs, "sub", "")
+	if sub != "" && slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	category := h.Utils.ReadStringQuery(qs, "category", "")
+	if category != "" && category != categoryTop && category != categoryControversial && category != categoryTopAndControversial {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid category"))
+		return fmt.Errorf("invalid category")
+	}
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	from, to, err := h.readDateRange(qs, time.UTC, 365)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	filters := data.Filters{
+		Page:         h.Utils.ReadIntQuery(qs, "page", 1),
+		PageSize:     h.Utils.ReadIntQuery(qs, "page_size", 10),
+		Sort:         h.Utils.ReadStringQuery(qs, "sort", sortRelevance),
+		SortSafelist: searchSorts,
+	}
+
+	if slices.Index(filters.SortSafelist, filters.Sort) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sort"))
+		return fmt.Errorf("invalid sort")
+	}
+
+	if err = h.Validate.Struct(filters); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	params := data.SearchParams{
+		Query:    q,
+		Sub:      sub,
+		Category: category,
+		From:     from,
+		To:       to,
+		PostType: postType,
+	}
+
+	results, metadata, err := h.Data.Posts.SearchPosts(params, filters)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error searching posts %v", err)
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, "search", results)
+	}
+
+	if len(results) < 1 {
+		return c.JSON(http.StatusOK, Cake{"posts": []data.SearchResult{}, "metadata": metadata})
+	}
+
+	return c.JSON(http.StatusOK, Cake{"posts": results, "metadata": metadata})
 }
 
 func (h *Handlers) UpdatePostsFromRedditHandler(c echo.Context) error {
@@ -222,11 +428,19 @@ func (h *Handlers) UpdatePostsFromRedditHandler(c echo.Context) error {
 	}
 
 	allPosts := append(topPosts, controversialPosts...)
+	h.attachTerms(allPosts)
+	h.attachFlairs(allPosts)
+	h.classifyPosts(allPosts)
 
 	if err = h.Data.Posts.InsertDailyPosts(allPosts); err != nil {
 		h.Utils.InternalServerError(c, err)
 		return err
 	}
+	h.recordSubredditStats(allPosts)
+	h.clusterStories(allPosts)
+	h.matchWatchlists(allPosts)
+	h.extractBoxOffice(allPosts)
+	h.Responses.Bump()
 
 	return c.JSON(http.StatusOK, Cake{"message": "Posts updated successfully"})
 }
@@ -243,15 +457,200 @@ func (h *Handlers) UpdatePostsFromReddit() error {
 	}
 
 	allPosts := append(topPosts, controversialPosts...)
+	h.attachTerms(allPosts)
+	h.attachFlairs(allPosts)
+	h.classifyPosts(allPosts)
 
 	if err = h.Data.Posts.InsertDailyPosts(allPosts); err != nil {
 		return err
 	}
+	h.recordSubredditStats(allPosts)
+	h.clusterStories(allPosts)
+	h.matchWatchlists(allPosts)
+	h.extractBoxOffice(allPosts)
+	h.Responses.Bump()
 
 	fmt.Println("Posts updated successfully")
 	return nil
 }
 
+// RebuildPostTerms extracts the terms of every stored post again, for when
+// the stopword or exclusion rules change.
+func (h *Handlers) RebuildPostTerms() error {
+	const batchSize = 500
+
+	var after string
+	var rebuilt int
+
+	for {
+		texts, err := h.Data.Posts.GetPostTextsAfter(after, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(texts) == 0 {
+			break
+		}
+
+		posts := make([]data.Post, 0, len(texts))
+		for _, t := range texts {
+			posts = append(posts, data.Post{ID: t.ID, Terms: h.termCounts(t.Text)})
+		}
+
+		if err := h.Data.Posts.ReplacePostTerms(posts); err != nil {
+			return err
+		}
+
+		rebuilt += len(texts)
+		after = texts[len(texts)-1].ID
+	}
+
+	log.Info("Rebuilt terms of posts: ", rebuilt)
+	return nil
+}
+
+// ReclassifyPosts labels every stored post with its type again, for when the
+// post type rules change.
+func (h *Handlers) ReclassifyPosts() error {
+	const batchSize = 500
+
+	var after string
+	var seen, changed int64
+
+	for {
+		titles, err := h.Data.Posts.GetPostTitlesAfter(after, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(titles) == 0 {
+			break
+		}
+
+		ids := make([]string, len(titles))
+		postTypes := make([]string, len(titles))
+		for i, t := range titles {
+			ids[i] = t.ID
+			postTypes[i] = h.Classifier.Classify(t.Subreddit, t.Title, t.Flair)
+		}
+
+		n, err := h.Data.Posts.UpdatePostTypes(ids, postTypes)
+		if err != nil {
+			return err
+		}
+
+		seen += int64(len(titles))
+		changed += n
+		after = titles[len(titles)-1].ID
+	}
+
+	h.Responses.Bump()
+
+	log.Info("Reclassified posts: ", seen, " changed: ", changed)
+	return nil
+}
+
+// classifyPosts labels the posts with their type. It runs after
+// attachFlairs, the flair is the strongest hint of the type.
+func (h *Handlers) classifyPosts(posts []data.Post) {
+	for i := range posts {
+		posts[i].PostType = h.Classifier.Classify(posts[i].Subreddit, posts[i].Title, posts[i].Flair)
+	}
+}
+
+// recordSubredditStats stores today's snapshot of every sub. The subscriber
+// count falls back to the one on the ingested posts when the about page can't
+// be fetched, and a sub that can't be counted either way is skipped.
+func (h *Handlers) recordSubredditStats(posts []data.Post) {
+	day := time.Now().UTC()
+
+	seen := make(map[string]map[string]bool)
+	subscribers := make(map[string]int)
+	for _, post := range posts {
+		sub := strings.ToLower(post.Subreddit)
+		if seen[sub] == nil {
+			seen[sub] = make(map[string]bool)
+		}
+		seen[sub][post.ID] = true
+		subscribers[sub] = max(subscribers[sub], post.SubredditSubscribers)
+	}
+
+	for _, sub := range subReddits {
+		stat := data.SubredditStat{
+			Subreddit:     sub,
+			Day:           day,
+			Subscribers:   subscribers[strings.ToLower(sub)],
+			PostsIngested: len(seen[strings.ToLower(sub)]),
+		}
+
+		about, _, err := h.Reddit.Subreddit.Get(context.Background(), sub)
+		if err != nil {
+			log.Error("Error getting about of ", sub, ": ", err)
+		} else {
+			stat.Subscribers = about.Subscribers
+			stat.ActiveUsers = about.ActiveUserCount
+		}
+
+		if stat.Subscribers == 0 {
+			continue
+		}
+
+		if err := h.Data.Stats.UpsertSubredditStat(stat); err != nil {
+			log.Error("Error recording stats of ", sub, ": ", err)
+		}
+	}
+}
+
+// flairBatchSize is the most ids /api/info takes in one go.
+const flairBatchSize = 100
+
+type flairListing struct {
+	Data struct {
+		Children []struct {
+			Data struct {
+				ID    string `json:"id"`
+				Flair string `json:"link_flair_text"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// attachFlairs looks up the link flair of the posts, which go-reddit leaves
+// out of its Post. A failed lookup only leaves the flairs empty, the stored
+// flair is kept on conflict then.
+func (h *Handlers) attachFlairs(posts []data.Post) {
+	index := make(map[string][]int)
+	var ids []string
+	for i, post := range posts {
+		if _, ok := index[post.ID]; !ok {
+			ids = append(ids, "t3_"+post.ID)
+		}
+		index[post.ID] = append(index[post.ID], i)
+	}
+
+	for start := 0; start < len(ids); start += flairBatchSize {
+		end := min(start+flairBatchSize, len(ids))
+
+		req, err := h.Reddit.NewRequest(http.MethodGet, "api/info?id="+strings.Join(ids[start:end], ","), nil)
+		if err != nil {
+			log.Error("Error building flair request: ", err)
+			return
+		}
+
+		var listing flairListing
+		if _, err := h.Reddit.Do(context.Background(), req, &listing); err != nil {
+			log.Error("Error getting flairs: ", err)
+			return
+		}
+
+		for _, child := range listing.Data.Children {
+			for _, i := range index[child.Data.ID] {
+				posts[i].Flair = child.Data.Flair
+			}
+		}
+	}
+}
+
 func GetDailyTopPosts(h *Handlers) ([]data.Post, error) {
 	var allPosts []data.Post
 
@@ -373,3 +772,22 @@ func getControversialFromReddit(Reddit *reddit.Client, limit int, interval strin
 	}
 	return allPosts, nil
 }
+
+// scoringProfile resolves ?scoring= against the configured profiles. Without
+// one a category is ranked by its own formula.
+func (h *Handlers) scoringProfile(category, name string) (data.ScoringProfile, error) {
+	if name == "" {
+		name = data.CategoryScoring(category)
+	}
+
+	profile, ok := h.Scoring[name]
+	if !ok {
+		return data.ScoringProfile{}, fmt.Errorf("unknown scoring profile %s", name)
+	}
+
+	return profile, nil
+}
+
+func (h *Handlers) GetScoringProfilesHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, Cake{"profiles": data.SortedScoringProfiles(h.Scoring)})
+}
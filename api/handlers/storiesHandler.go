@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/internal/minhash"
+)
+
+const (
+	// storySimilarity is how much of a post has to match a post of a story
+	// to join it.
+	storySimilarity = 0.5
+
+	// storyWindow is how far apart two posts of the same story can be.
+	storyWindow = 72 * time.Hour
+
+	// storyBodyLength is how much of the body counts next to the title. The
+	// same news is often a link post once and a text post with the article
+	// pasted in the next time, so the body can't weigh as much as the title.
+	storyBodyLength = 200
+)
+
+var storySorts = []string{"posts", "engagement", "recent"}
+
+func storyText(post data.Post) string {
+	body := []rune(post.Selftext)
+	if len(body) > storyBodyLength {
+		body = body[:storyBodyLength]
+	}
+	return post.Title + " " + string(body)
+}
+
+// clusterStories puts every new post in the story of the most similar post
+// posted around the same time, or in a story of its own. It runs after the
+// posts are stored, oldest first so a story is led by its first post. A
+// failure only leaves the rest of the posts out of the stories.
+func (h *Handlers) clusterStories(posts []data.Post) {
+	unique := make(map[string]data.Post, len(posts))
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		if _, ok := unique[post.ID]; !ok {
+			ids = append(ids, post.ID)
+		}
+		unique[post.ID] = post
+	}
+
+	signed, err := h.Data.Stories.GetSignedPosts(ids)
+	if err != nil {
+		log.Error("Error getting signed posts: ", err)
+		return
+	}
+
+	pending := make([]data.Post, 0, len(unique))
+	for _, post := range unique {
+		if !signed[post.ID] {
+			pending = append(pending, post)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedUTC.Before(pending[j].CreatedUTC)
+	})
+
+	var joined int
+	for _, post := range pending {
+		signature := h.Minhash.Signature(storyText(post))
+		if signature == nil {
+			continue
+		}
+		bands := h.Minhash.Bands(signature)
+
+		candidates, err := h.Data.Stories.GetStoryCandidates(post.Subreddit, bands, post.CreatedUTC.Add(-storyWindow), post.CreatedUTC.Add(storyWindow))
+		if err != nil {
+			log.Error("Error getting story candidates: ", err)
+			return
+		}
+
+		clusterID, similarity := 0, 0.0
+		for _, candidate := range candidates {
+			if s := minhash.Similarity(signature, candidate.Signature); s >= storySimilarity && s > similarity {
+				clusterID, similarity = candidate.ClusterID, s
+			}
+		}
+
+		// a post leading its own story matches it fully
+		if clusterID == 0 {
+			similarity = 1
+		}
+
+		if _, err := h.Data.Stories.AssignStory(post, clusterID, signature, bands, similarity); err != nil {
+			log.Error("Error assigning story: ", err)
+			return
+		}
+
+		if clusterID != 0 {
+			joined++
+		}
+	}
+
+	log.Info("Clustered posts: ", len(pending), " joined stories: ", joined)
+}
+
+// GetStoriesHandler lists the stories that were posted more than once.
+func (h *Handlers) GetStoriesHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	from, to, err := h.readDateRange(qs, time.UTC, 30)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	minPosts := h.Utils.ReadIntQuery(qs, "min_posts", 2)
+	if minPosts < 1 || minPosts > 100 {
+		h.Utils.BadRequest(c, fmt.Errorf("min_posts must be between 1 and 100"))
+		return fmt.Errorf("invalid min_posts")
+	}
+
+	filters := data.Filters{
+		Page:         h.Utils.ReadIntQuery(qs, "page", 1),
+		PageSize:     h.Utils.ReadIntQuery(qs, "page_size", 10),
+		Sort:         h.Utils.ReadStringQuery(qs, "sort", "posts"),
+		SortSafelist: storySorts,
+	}
+
+	if slices.Index(filters.SortSafelist, filters.Sort) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sort"))
+		return fmt.Errorf("invalid sort")
+	}
+
+	if err = h.Validate.Struct(filters); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	stories, metadata, err := h.Data.Stories.GetStories(sub, postType, from, to, minPosts, filters)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting stories %v", err)
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_stories", sub), stories)
+	}
+
+	return c.JSON(http.StatusOK, Cake{"stories": stories, "metadata": metadata})
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/internal/topics"
+)
+
+const (
+	// minTopicPosts is the fewest posts with terms a month needs to be split
+	// into topics at all.
+	minTopicPosts   = 30
+	topicLabelTerms = 3
+	topicPosts      = 3
+
+	// topicContinuity is how alike a topic has to be to one of the month
+	// before to count as the same topic carrying on.
+	topicContinuity = 0.3
+
+	topicStatusNew        = "new"
+	topicStatusContinuing = "continuing"
+)
+
+type TopicTrend struct {
+	ID            int                 `json:"id"`
+	Rank          int                 `json:"rank"`
+	Label         string              `json:"label"`
+	Status        string              `json:"status"`
+	PreviousID    *int                `json:"previous_id"`
+	Continuity    float64             `json:"continuity"`
+	PostCount     int                 `json:"post_count"`
+	Share         float64             `json:"share"`
+	ScoreTotal    int                 `json:"score_total"`
+	CommentsTotal int                 `json:"comments_total"`
+	Terms         []topics.TermWeight `json:"terms"`
+	Posts         []data.TopicPost    `json:"posts"`
+}
+
+type FadedTopic struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+type TopicMonth struct {
+	Month  time.Time    `json:"month"`
+	Topics []TopicTrend `json:"topics"`
+	// Faded are the topics of the month before that nothing carried on.
+	Faded []FadedTopic `json:"faded"`
+}
+
+// TopicRow is a topic of a month, for the csv and ndjson exports.
+type TopicRow struct {
+	Month      time.Time `json:"month"`
+	ID         int       `json:"id"`
+	Rank       int       `json:"rank"`
+	Label      string    `json:"label"`
+	Status     string    `json:"status"`
+	PreviousID *int      `json:"previous_id"`
+	Continuity float64   `json:"continuity"`
+	PostCount  int       `json:"post_count"`
+	Share      float64   `json:"share"`
+	Terms      string    `json:"terms"`
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// buildTopics clusters the posts of a sub's month of the type, or of every
+// type when postType is empty, and stores the topics in place of the ones it
+// had.
+func (h *Handlers) buildTopics(sub, postType string, month time.Time) error {
+	docs, err := h.Data.Topics.GetMonthDocuments(sub, postType, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return err
+	}
+
+	var monthTopics []data.Topic
+
+	if len(docs) >= minTopicPosts {
+		posts := make(map[string]data.TopicPost, len(docs))
+		documents := make([]topics.Document, len(docs))
+		for i, doc := range docs {
+			posts[doc.Post.ID] = doc.Post
+			documents[i] = topics.Document{ID: doc.Post.ID, Terms: doc.Terms}
+		}
+
+		clusters := topics.Cluster(documents, topics.DefaultOptions)
+
+		var clustered int
+		for _, cluster := range clusters {
+			clustered += len(cluster.Members)
+		}
+
+		for i, cluster := range clusters {
+			topic := data.Topic{
+				Rank:      i + 1,
+				Terms:     cluster.Terms,
+				Posts:     []data.TopicPost{},
+				PostCount: len(cluster.Members),
+				Share:     math.Round(float64(len(cluster.Members))/float64(clustered)*10000) / 10000,
+			}
+
+			labels := make([]string, 0, topicLabelTerms)
+			for _, term := range cluster.Terms[:min(topicLabelTerms, len(cluster.Terms))] {
+				labels = append(labels, term.Term)
+			}
+			topic.Label = strings.Join(labels, ", ")
+
+			for j, member := range cluster.Members {
+				post := posts[member.ID]
+				topic.ScoreTotal += post.Upvotes
+				topic.CommentsTotal += post.NumComments
+
+				if j < topicPosts {
+					post.Similarity = math.Round(member.Similarity*1000) / 1000
+					topic.Posts = append(topic.Posts, post)
+				}
+			}
+
+			for j := range topic.Terms {
+				topic.Terms[j].Weight = math.Round(topic.Terms[j].Weight*1000) / 1000
+			}
+
+			monthTopics = append(monthTopics, topic)
+		}
+	}
+
+	return h.Data.Topics.ReplaceTopics(sub, postType, month, monthTopics)
+}
+
+// BuildMonthlyTopics builds the topics of the month that just ended.
+func (h *Handlers) BuildMonthlyTopics() error {
+	return h.BuildTopics(1)
+}
+
+// BuildTopics builds the topics of every sub for the given number of months
+// before the current one, of all the posts and of every type on its own.
+func (h *Handlers) BuildTopics(months int) error {
+	current := monthStart(time.Now().UTC())
+	postTypes := append([]string{""}, h.Classifier.Types()...)
+
+	var failed []string
+	for i := 1; i <= months; i++ {
+		m := current.AddDate(0, -i, 0)
+		for _, sub := range subReddits {
+			for _, postType := range postTypes {
+				if err := h.buildTopics(sub, postType, m); err != nil {
+					log.Error("Error building topics for ", sub, " ", postType, " ", m.Format("2006-01"), ": ", err)
+					if slices.Index(failed, sub) == -1 {
+						failed = append(failed, sub)
+					}
+				}
+			}
+		}
+	}
+
+	h.Responses.Bump()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("topics failed for %v", failed)
+	}
+
+	return nil
+}
+
+// trendTopics links every topic to the most alike topic of the month before,
+// and lists the topics of the month before nothing was linked to.
+func trendTopics(months []time.Time, byMonth map[time.Time][]data.Topic, previous []data.Topic) []TopicMonth {
+	trends := make([]TopicMonth, 0, len(months))
+
+	for _, month := range months {
+		current := byMonth[month]
+		carried := make(map[int]bool)
+
+		tm := TopicMonth{Month: month, Topics: []TopicTrend{}, Faded: []FadedTopic{}}
+		for _, topic := range current {
+			trend := TopicTrend{
+				ID:            topic.ID,
+				Rank:          topic.Rank,
+				Label:         topic.Label,
+				Status:        topicStatusNew,
+				PostCount:     topic.PostCount,
+				Share:         topic.Share,
+				ScoreTotal:    topic.ScoreTotal,
+				CommentsTotal: topic.CommentsTotal,
+				Terms:         topic.Terms,
+				Posts:         topic.Posts,
+			}
+
+			for _, p := range previous {
+				if sim := topics.Similarity(topic.Terms, p.Terms); sim >= topicContinuity && sim > trend.Continuity {
+					id := p.ID
+					trend.PreviousID = &id
+					trend.Continuity = math.Round(sim*1000) / 1000
+					trend.Status = topicStatusContinuing
+				}
+			}
+
+			if trend.PreviousID != nil {
+				carried[*trend.PreviousID] = true
+			}
+
+			tm.Topics = append(tm.Topics, trend)
+		}
+
+		for _, p := range previous {
+			if !carried[p.ID] {
+				tm.Faded = append(tm.Faded, FadedTopic{ID: p.ID, Label: p.Label})
+			}
+		}
+
+		trends = append(trends, tm)
+		previous = current
+	}
+
+	return trends
+}
+
+func topicRows(trends []TopicMonth) []TopicRow {
+	var rows []TopicRow
+	for _, tm := range trends {
+		for _, t := range tm.Topics {
+			terms := make([]string, len(t.Terms))
+			for i, term := range t.Terms {
+				terms[i] = term.Term
+			}
+
+			rows = append(rows, TopicRow{
+				Month:      tm.Month,
+				ID:         t.ID,
+				Rank:       t.Rank,
+				Label:      t.Label,
+				Status:     t.Status,
+				PreviousID: t.PreviousID,
+				Continuity: t.Continuity,
+				PostCount:  t.PostCount,
+				Share:      t.Share,
+				Terms:      strings.Join(terms, " "),
+			})
+		}
+	}
+	return rows
+}
+
+func (h *Handlers) GetTopicsHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	postType, err := h.readPostType(c.QueryParams())
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	n := h.Utils.ReadIntQuery(c.QueryParams(), "months", 6)
+	if n < 1 || n > 12 {
+		h.Utils.BadRequest(c, fmt.Errorf("months must be between 1 and 12"))
+		return fmt.Errorf("invalid months")
+	}
+
+	// the month in progress has no topics until it is over
+	to := monthStart(time.Now().UTC())
+	from := to.AddDate(0, -n, 0)
+
+	// one month more, to tell whether the first month's topics are new
+	stored, err := h.Data.Topics.GetTopics(sub, postType, from.AddDate(0, -1, 0), to)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting topics %v", err)
+	}
+
+	byMonth := make(map[time.Time][]data.Topic)
+	for _, topic := range stored {
+		month := monthStart(topic.Month)
+		byMonth[month] = append(byMonth[month], topic)
+	}
+
+	months := make([]time.Time, n)
+	for i := range months {
+		months[i] = from.AddDate(0, i, 0)
+	}
+
+	trends := trendTopics(months, byMonth, byMonth[from.AddDate(0, -1, 0)])
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_topics", sub), topicRows(trends))
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"from":   from,
+		"to":     to,
+		"months": trends,
+	})
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/vartanbeno/go-reddit/v2/reddit"
+)
+
+const (
+	// reddit hands out at most this many posts a listing
+	trackingListingLimit = 100
+
+	// maxTrackingWindow keeps a forgotten window from polling forever.
+	maxTrackingWindow = 30 * 24 * time.Hour
+
+	trackingTopPosts = 10
+
+	defaultTrackingBucket = 15
+	minTrackingBucket     = 5
+	maxTrackingBucket     = 24 * 60
+
+	// trackingFetchTimeout keeps a hung listing from holding up the next poll.
+	trackingFetchTimeout = 30 * time.Second
+)
+
+// trackedListing is a post from the new or hot listing, or both.
+type trackedListing struct {
+	post  *reddit.Post
+	inNew bool
+	inHot bool
+}
+
+// containsPhrase reports whether the phrase appears in the text as whole
+// words, ignoring case. Keywords are often in Tamil or Telugu script, where
+// regexp's \b doesn't work, so the boundaries are checked by hand.
+func containsPhrase(text, phrase string) bool {
+	text = strings.ToLower(text)
+	for i := 0; i <= len(text); {
+		j := strings.Index(text[i:], phrase)
+		if j == -1 {
+			return false
+		}
+		start, end := i+j, i+j+len(phrase)
+
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+
+		_, size := utf8.DecodeRuneInString(text[start:])
+		i = start + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r))
+}
+
+// readTrackingWindow reads and normalizes a window from the body. Keywords
+// are matched case-insensitively, so they are stored lowercased.
+func (h *Handlers) readTrackingWindow(c echo.Context) (*data.TrackingWindow, error) {
+	var input data.TrackingWindow
+	if err := h.Utils.ReadJSON(c, &input); err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading json; %v", err))
+		return nil, err
+	}
+
+	input.Title = strings.TrimSpace(input.Title)
+
+	var keywords []string
+	for _, k := range input.Keywords {
+		k = strings.ToLower(strings.Join(strings.Fields(k), " "))
+		if slices.Index(keywords, k) == -1 {
+			keywords = append(keywords, k)
+		}
+	}
+	input.Keywords = keywords
+
+	if err := h.Validate.Struct(input); err != nil {
+		h.Utils.ValidationError(c, err)
+		return nil, err
+	}
+
+	if slices.Index(subReddits, input.Subreddit) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return nil, fmt.Errorf("invalid sub")
+	}
+
+	if !input.EndTime.After(input.StartTime) {
+		h.Utils.BadRequest(c, fmt.Errorf("end_time must be after start_time"))
+		return nil, fmt.Errorf("invalid window")
+	}
+
+	if input.EndTime.Sub(input.StartTime) > maxTrackingWindow {
+		h.Utils.BadRequest(c, fmt.Errorf("a window must not be longer than %d days", int(maxTrackingWindow.Hours()/24)))
+		return nil, fmt.Errorf("invalid window")
+	}
+
+	input.CreatedBy = c.Get("reddit_uid").(string)
+
+	return &input, nil
+}
+
+// fetchTrackingListings polls the sub's new and hot listings.
+func (h *Handlers) fetchTrackingListings(sub string) ([]*trackedListing, error) {
+	var listings []*trackedListing
+	index := make(map[string]*trackedListing)
+
+	add := func(posts []*reddit.Post, hot bool) {
+		for _, post := range posts {
+			l, ok := index[post.ID]
+			if !ok {
+				l = &trackedListing{post: post}
+				index[post.ID] = l
+				listings = append(listings, l)
+			}
+			if hot {
+				l.inHot = true
+			} else {
+				l.inNew = true
+			}
+		}
+	}
+
+	opts := &reddit.ListOptions{Limit: trackingListingLimit}
+
+	ctx, cancel := context.WithTimeout(context.Background(), trackingFetchTimeout)
+	defer cancel()
+
+	newPosts, _, err := h.Reddit.Subreddit.NewPosts(ctx, sub, opts)
+	if err != nil {
+		return nil, err
+	}
+	add(newPosts, false)
+
+	hotPosts, _, err := h.Reddit.Subreddit.HotPosts(ctx, sub, opts)
+	if err != nil {
+		return nil, err
+	}
+	add(hotPosts, true)
+
+	return listings, nil
+}
+
+// PollTrackingWindows snapshots the new and hot posts matching the running
+// windows. Only posts made since the window opened are tracked.
+func (h *Handlers) PollTrackingWindows() error {
+	windows, err := h.Data.Tracking.GetWindows(true)
+	if err != nil {
+		return err
+	}
+
+	if len(windows) == 0 {
+		return nil
+	}
+
+	bySub := make(map[string][]data.TrackingWindow)
+	for _, w := range windows {
+		bySub[w.Subreddit] = append(bySub[w.Subreddit], w)
+	}
+
+	capturedAt := time.Now().UTC()
+
+	var snapshots []data.TrackingSnapshot
+	var failed []string
+
+	for sub, subWindows := range bySub {
+		listings, err := h.fetchTrackingListings(sub)
+		if err != nil {
+			log.Error("Error polling ", sub, " for tracking: ", err)
+			failed = append(failed, sub)
+			continue
+		}
+
+		for _, w := range subWindows {
+			for _, l := range listings {
+				post := l.post
+				if post.Created.Time.Before(w.StartTime) {
+					continue
+				}
+
+				text := post.Title + "\n" + post.Body
+				if !slices.ContainsFunc(w.Keywords, func(k string) bool { return containsPhrase(text, k) }) {
+					continue
+				}
+
+				snapshots = append(snapshots, data.TrackingSnapshot{
+					WindowID:    w.ID,
+					PostID:      post.ID,
+					Title:       post.Title,
+					Author:      post.Author,
+					Permalink:   post.Permalink,
+					Score:       post.Score,
+					NumComments: post.NumberOfComments,
+					UpvoteRatio: float64(post.UpvoteRatio),
+					CreatedUTC:  post.Created.Time,
+					InNew:       l.inNew,
+					InHot:       l.inHot,
+					CapturedAt:  capturedAt,
+				})
+			}
+		}
+	}
+
+	if err := h.Data.Tracking.InsertSnapshots(snapshots); err != nil {
+		return err
+	}
+
+	log.Info("Tracking windows: ", len(windows), " snapshots: ", len(snapshots))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("tracking polls failed for %v", failed)
+	}
+
+	return nil
+}
+
+func (h *Handlers) GetTrackingWindowsHandler(c echo.Context) error {
+	activeOnly, err := h.readBoolQuery(c.QueryParams(), "active", false)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	windows, err := h.Data.Tracking.GetWindows(activeOnly)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"windows": windows})
+}
+
+func (h *Handlers) CreateTrackingWindowHandler(c echo.Context) error {
+	input, err := h.readTrackingWindow(c)
+	if err != nil {
+		return err
+	}
+
+	if !input.EndTime.After(time.Now()) {
+		h.Utils.BadRequest(c, fmt.Errorf("end_time must be in the future"))
+		return fmt.Errorf("invalid window")
+	}
+
+	if err := h.Data.Tracking.InsertWindow(input); err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, Cake{"window": input})
+}
+
+func (h *Handlers) UpdateTrackingWindowHandler(c echo.Context) error {
+	id, err := h.Utils.ReadIntParam(c, "id")
+	if err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading id; %v", err))
+		return err
+	}
+
+	input, err := h.readTrackingWindow(c)
+	if err != nil {
+		return err
+	}
+	input.ID = id
+
+	if !input.EndTime.After(time.Now()) {
+		h.Utils.BadRequest(c, fmt.Errorf("end_time must be in the future"))
+		return fmt.Errorf("invalid window")
+	}
+
+	if err := h.Data.Tracking.UpdateWindow(input); err != nil {
+		if errors.Is(err, data.ErrTrackingWindowNotFound) {
+			h.Utils.NotFoundResponse(c)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"window": input})
+}
+
+func (h *Handlers) DeleteTrackingWindowHandler(c echo.Context) error {
+	id, err := h.Utils.ReadIntParam(c, "id")
+	if err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading id; %v", err))
+		return err
+	}
+
+	if err := h.Data.Tracking.DeleteWindow(id); err != nil {
+		if errors.Is(err, data.ErrTrackingWindowNotFound) {
+			h.Utils.NotFoundResponse(c)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"message": "tracking window deleted"})
+}
+
+// GetTrackingVolumeHandler charts the discussion of a tracking window as it
+// is polled, in buckets of ?bucket= minutes. It reads straight from the
+// snapshots, so it isn't cached.
+func (h *Handlers) GetTrackingVolumeHandler(c echo.Context) error {
+	id, err := h.Utils.ReadIntParam(c, "id")
+	if err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading id; %v", err))
+		return err
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	bucket := h.Utils.ReadIntQuery(c.QueryParams(), "bucket", defaultTrackingBucket)
+	if bucket < minTrackingBucket || bucket > maxTrackingBucket {
+		h.Utils.BadRequest(c, fmt.Errorf("bucket must be between %d and %d minutes", minTrackingBucket, maxTrackingBucket))
+		return fmt.Errorf("invalid bucket")
+	}
+
+	window, err := h.Data.Tracking.GetWindow(id)
+	if err != nil {
+		if errors.Is(err, data.ErrTrackingWindowNotFound) {
+			h.Utils.NotFoundResponse(c)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	points, err := h.Data.Tracking.GetVolume(id, time.Duration(bucket)*time.Minute)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("tracking_%d_volume", id), points)
+	}
+
+	posts, err := h.Data.Tracking.GetTopPosts(id, trackingTopPosts)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	now := time.Now()
+	live := !now.Before(window.StartTime) && now.Before(window.EndTime)
+
+	return c.JSON(http.StatusOK, Cake{
+		"window": window,
+		"live":   live,
+		"bucket": bucket,
+		"volume": points,
+		"posts":  posts,
+	})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import "testing"
+
+func TestContainsPhrase(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		phrase string
+		want   bool
+	}{
+		{name: "whole word", text: "Watched Jawan today", phrase: "jawan", want: true},
+		{name: "ignores case", text: "JAWAN FDFS", phrase: "jawan", want: true},
+		{name: "inside a word", text: "jawans everywhere", phrase: "jawan", want: false},
+		{name: "prefix of a word", text: "superjawan", phrase: "jawan", want: false},
+		{name: "punctuation is a boundary", text: "(jawan)!", phrase: "jawan", want: true},
+		{name: "multi word phrase", text: "the leo trailer is out", phrase: "leo trailer", want: true},
+		{name: "later occurrence matches", text: "jawans and then jawan", phrase: "jawan", want: true},
+		{name: "no occurrence", text: "pathaan review", phrase: "jawan", want: false},
+		{name: "tamil word", text: "லியோ படம் பார்த்தேன்", phrase: "லியோ", want: true},
+		{name: "tamil vowel sign continues the word", text: "லியோவை பார்த்தேன்", phrase: "லியோ", want: false},
+		{name: "telugu word", text: "సలార్ ట్రైలర్", phrase: "సలార్", want: true},
+		{name: "telugu sign continues the word", text: "సలారు", phrase: "సలార", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsPhrase(tt.text, tt.phrase); got != tt.want {
+				t.Errorf("containsPhrase(%q, %q) = %v, want %v", tt.text, tt.phrase, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/internal/logodds"
+)
+
+const (
+	maxDistinctiveTerms = 100
+
+	// a term said only a handful of times is distinctive of nothing
+	defaultMinTermCount = 10
+)
+
+// readAgainstSubs reads the comma separated subs to compare sub with, every
+// other sub when none are given.
+func readAgainstSubs(value, sub string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		var others []string
+		for _, s := range subReddits {
+			if s != sub {
+				others = append(others, s)
+			}
+		}
+		return others, nil
+	}
+
+	var others []string
+	for _, part := range strings.Split(value, ",") {
+		s := strings.TrimSpace(part)
+		if slices.Index(subReddits, s) == -1 {
+			return nil, fmt.Errorf("invalid sub %q", part)
+		}
+		if s == sub {
+			return nil, fmt.Errorf("against must not have %s", sub)
+		}
+		if slices.Index(others, s) == -1 {
+			others = append(others, s)
+		}
+	}
+
+	return others, nil
+}
+
+// GetDistinctiveTermsHandler lists the terms that set the sub's posts apart
+// from the other subs' and the terms the others use that the sub doesn't,
+// by their weighted log-odds ratio.
+func (h *Handlers) GetDistinctiveTermsHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+
+	against, err := readAgainstSubs(qs.Get("against"), sub)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	from, to, err := h.readDateRange(qs, time.UTC, 30)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	limit := h.Utils.ReadIntQuery(qs, "limit", 25)
+	if limit < 1 || limit > maxDistinctiveTerms {
+		h.Utils.BadRequest(c, fmt.Errorf("limit must be between 1 and %d", maxDistinctiveTerms))
+		return fmt.Errorf("invalid limit")
+	}
+
+	minCount := h.Utils.ReadIntQuery(qs, "min_count", defaultMinTermCount)
+	if minCount < 1 {
+		h.Utils.BadRequest(c, fmt.Errorf("min_count must be at least 1"))
+		return fmt.Errorf("invalid min_count")
+	}
+
+	counts, otherCounts, err := h.Data.Posts.GetSubredditTermCounts(sub, against, postType, from, to)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting term counts %v", err)
+	}
+
+	scores := logodds.Compare(counts, otherCounts, logodds.DefaultPrior, minCount)
+
+	distinctive := []logodds.Score{}
+	for _, s := range scores {
+		if s.Z <= 0 || len(distinctive) == limit {
+			break
+		}
+		distinctive = append(distinctive, s)
+	}
+
+	others := []logodds.Score{}
+	for i := len(scores) - 1; i >= 0; i-- {
+		if scores[i].Z >= 0 || len(others) == limit {
+			break
+		}
+		others = append(others, scores[i])
+	}
+
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_distinctive", sub), append(distinctive, others...))
+	}
+
+	return c.JSON(http.StatusOK, Cake{
+		"sub":         sub,
+		"against":     against,
+		"from":        from,
+		"to":          to,
+		"distinctive": distinctive,
+		"others":      others,
+	})
+}
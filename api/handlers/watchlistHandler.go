@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/utils"
+)
+
+const (
+	maxWatchlists = 50
+
+	// watchlistBackfill is how far back a new or changed keyword is matched,
+	// so its feed doesn't start out empty.
+	watchlistBackfill = 7 * 24 * time.Hour
+)
+
+type markReadInput struct {
+	// PostIDs are the posts to mark read. None marks every match read.
+	PostIDs []string `json:"post_ids" validate:"max=100"`
+}
+
+// readWatchlist reads and normalizes the keyword and sub of a watchlist from
+// the body. Keywords are matched case-insensitively, so they are stored
+// lowercased to keep the same keyword from being added twice.
+func (h *Handlers) readWatchlist(c echo.Context) (*data.Watchlist, error) {
+	var input data.Watchlist
+	if err := h.Utils.ReadJSON(c, &input); err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading json; %v", err))
+		return nil, err
+	}
+
+	input.Keyword = strings.ToLower(strings.Join(strings.Fields(input.Keyword), " "))
+
+	if err := h.Validate.Struct(input); err != nil {
+		h.Utils.ValidationError(c, err)
+		return nil, err
+	}
+
+	if input.Subreddit != "" && slices.Index(subReddits, input.Subreddit) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return nil, fmt.Errorf("invalid sub")
+	}
+
+	input.RedditUID = c.Get("reddit_uid").(string)
+
+	return &input, nil
+}
+
+// matchWatchlists adds the new posts to the feeds of the watchlists they
+// match. It runs after the posts are stored, and a failure only leaves the
+// posts out of the feeds.
+func (h *Handlers) matchWatchlists(posts []data.Post) {
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	matched, err := h.Data.Watchlists.MatchPosts(ids)
+	if err != nil {
+		log.Error("Error matching watchlists: ", err)
+		return
+	}
+
+	log.Info("Watchlist matches: ", matched)
+}
+
+func (h *Handlers) GetWatchlistsHandler(c echo.Context) error {
+	reddit_uid := c.Get("reddit_uid").(string)
+
+	watchlists, err := h.Data.Watchlists.GetWatchlists(reddit_uid)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"watchlist": watchlists})
+}
+
+func (h *Handlers) CreateWatchlistHandler(c echo.Context) error {
+	input, err := h.readWatchlist(c)
+	if err != nil {
+		return err
+	}
+
+	count, err := h.Data.Watchlists.CountWatchlists(input.RedditUID)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	if count >= maxWatchlists {
+		err := fmt.Errorf("watchlist limit reached")
+		h.Utils.CustomErrorResponse(c, utils.Cake{"error": fmt.Sprintf("you can watch at most %d keywords", maxWatchlists)}, http.StatusUnprocessableEntity, err)
+		return err
+	}
+
+	if err := h.Data.Watchlists.InsertWatchlist(input, time.Now().Add(-watchlistBackfill)); err != nil {
+		if errors.Is(err, data.ErrDuplicateWatchlist) {
+			h.Utils.CustomErrorResponse(c, utils.Cake{"error": err.Error()}, http.StatusConflict, err)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, Cake{"watchlist": input})
+}
+
+func (h *Handlers) UpdateWatchlistHandler(c echo.Context) error {
+	id, err := h.Utils.ReadIntParam(c, "id")
+	if err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading id; %v", err))
+		return err
+	}
+
+	input, err := h.readWatchlist(c)
+	if err != nil {
+		return err
+	}
+	input.ID = id
+
+	if err := h.Data.Watchlists.UpdateWatchlist(input, time.Now().Add(-watchlistBackfill)); err != nil {
+		switch {
+		case errors.Is(err, data.ErrWatchlistNotFound):
+			h.Utils.NotFoundResponse(c)
+		case errors.Is(err, data.ErrDuplicateWatchlist):
+			h.Utils.CustomErrorResponse(c, utils.Cake{"error": err.Error()}, http.StatusConflict, err)
+		default:
+			h.Utils.InternalServerError(c, err)
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"watchlist": input})
+}
+
+func (h *Handlers) DeleteWatchlistHandler(c echo.Context) error {
+	reddit_uid := c.Get("reddit_uid").(string)
+
+	id, err := h.Utils.ReadIntParam(c, "id")
+	if err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading id; %v", err))
+		return err
+	}
+
+	if err := h.Data.Watchlists.DeleteWatchlist(id, reddit_uid); err != nil {
+		if errors.Is(err, data.ErrWatchlistNotFound) {
+			h.Utils.NotFoundResponse(c)
+			return err
+		}
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"message": "watchlist deleted"})
+}
+
+// GetWatchlistFeedHandler lists the posts that matched the user's keywords,
+// newest match first, with whether each one was read.
+func (h *Handlers) GetWatchlistFeedHandler(c echo.Context) error {
+	reddit_uid := c.Get("reddit_uid").(string)
+
+	qs := c.QueryParams()
+
+	unreadOnly, err := h.readBoolQuery(qs, "unread", false)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	watchlistID := h.Utils.ReadIntQuery(qs, "watchlist", 0)
+	if watchlistID < 0 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid watchlist"))
+		return fmt.Errorf("invalid watchlist")
+	}
+
+	filters := data.Filters{
+		Page:     h.Utils.ReadIntQuery(qs, "page", 1),
+		PageSize: h.Utils.ReadIntQuery(qs, "page_size", 20),
+	}
+
+	if err = h.Validate.Struct(filters); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	posts, metadata, err := h.Data.Watchlists.GetFeed(reddit_uid, watchlistID, unreadOnly, filters)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	unread, err := h.Data.Watchlists.GetUnreadCount(reddit_uid)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"posts": posts, "unread": unread, "metadata": metadata})
+}
+
+func (h *Handlers) MarkWatchlistFeedReadHandler(c echo.Context) error {
+	reddit_uid := c.Get("reddit_uid").(string)
+
+	var input markReadInput
+	if err := h.Utils.ReadJSON(c, &input); err != nil {
+		h.Utils.BadRequest(c, fmt.Errorf("error in reading json; %v", err))
+		return err
+	}
+
+	if err := h.Validate.Struct(input); err != nil {
+		h.Utils.ValidationError(c, err)
+		return err
+	}
+
+	marked, err := h.Data.Watchlists.MarkRead(reddit_uid, input.PostIDs)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, Cake{"marked": marked})
+}
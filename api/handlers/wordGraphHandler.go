@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// 10.83 is the chi-squared critical value for p < 0.001 at one degree of
+	// freedom, which the log-likelihood ratio follows.
+	defaultMinEdgeScore = 10.83
+	minEdgeDocs         = 2
+)
+
+type WordNode struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+	Posts int    `json:"posts"`
+}
+
+type WordEdge struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Weight int     `json:"weight"`
+	Score  float64 `json:"score"`
+}
+
+type WordGraph struct {
+	Nodes []WordNode `json:"nodes"`
+	Edges []WordEdge `json:"edges"`
+}
+
+func xlogx(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return x * math.Log(x)
+}
+
+// logLikelihood is Dunning's G² for a 2x2 table of posts with both terms (k11),
+// only the first (k12), only the second (k21) and neither (k22).
+func logLikelihood(k11, k12, k21, k22 float64) float64 {
+	n := k11 + k12 + k21 + k22
+	rows := xlogx(k11+k12) + xlogx(k21+k22)
+	cols := xlogx(k11+k21) + xlogx(k12+k22)
+	cells := xlogx(k11) + xlogx(k12) + xlogx(k21) + xlogx(k22)
+	return 2 * (cells + xlogx(n) - rows - cols)
+}
+
+// buildWordGraph links the most used terms by the posts they share, keeping
+// only pairs that show up together more often than chance would have it.
+func buildWordGraph(docs [][]string, maxNodes int, minScore float64) WordGraph {
+	counts := make(map[string]int)
+	docFreq := make(map[string]int)
+	docSets := make([][]string, 0, len(docs))
+
+	for _, tokens := range docs {
+		seen := make(map[string]bool)
+		for _, t := range tokens {
+			counts[t]++
+			if !seen[t] {
+				seen[t] = true
+				docFreq[t]++
+			}
+		}
+
+		set := make([]string, 0, len(seen))
+		for t := range seen {
+			set = append(set, t)
+		}
+		docSets = append(docSets, set)
+	}
+
+	terms := make([]string, 0, len(counts))
+	for t := range counts {
+		terms = append(terms, t)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if counts[terms[i]] == counts[terms[j]] {
+			return terms[i] < terms[j]
+		}
+		return counts[terms[i]] > counts[terms[j]]
+	})
+	if len(terms) > maxNodes {
+		terms = terms[:maxNodes]
+	}
+
+	graph := WordGraph{Nodes: []WordNode{}, Edges: []WordEdge{}}
+	isNode := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		isNode[t] = true
+		graph.Nodes = append(graph.Nodes, WordNode{ID: t, Count: counts[t], Posts: docFreq[t]})
+	}
+
+	type pair struct{ a, b string }
+	together := make(map[pair]int)
+
+	for _, set := range docSets {
+		var nodes []string
+		for _, t := range set {
+			if isNode[t] {
+				nodes = append(nodes, t)
+			}
+		}
+		sort.Strings(nodes)
+
+		for i := 0; i < len(nodes); i++ {
+			for j := i + 1; j < len(nodes); j++ {
+				together[pair{nodes[i], nodes[j]}]++
+			}
+		}
+	}
+
+	n := float64(len(docs))
+	for p, k := range together {
+		if k < minEdgeDocs {
+			continue
+		}
+
+		k11 := float64(k)
+		k12 := float64(docFreq[p.a]) - k11
+		k21 := float64(docFreq[p.b]) - k11
+		k22 := n - k11 - k12 - k21
+
+		// only attraction makes an edge, terms that avoid each other score high too
+		if k11*n <= float64(docFreq[p.a])*float64(docFreq[p.b]) {
+			continue
+		}
+
+		score := logLikelihood(k11, k12, k21, k22)
+		if score < minScore {
+			continue
+		}
+
+		graph.Edges = append(graph.Edges, WordEdge{
+			Source: p.a,
+			Target: p.b,
+			Weight: k,
+			Score:  math.Round(score*100) / 100,
+		})
+	}
+
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		return graph.Edges[i].Score > graph.Edges[j].Score
+	})
+
+	return graph
+}
+
+func (h *Handlers) GetWordGraphHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	format, err := h.readExportFormat(c)
+	if err != nil {
+		return err
+	}
+
+	qs := c.QueryParams()
+
+	interval := h.Utils.ReadStringQuery(qs, "interval", intervalMonth)
+	if interval != intervalWeek && interval != intervalMonth {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid interval"))
+		return fmt.Errorf("invalid interval")
+	}
+
+	intervalInt := 30
+	if interval == intervalWeek {
+		intervalInt = 7
+	}
+
+	postType, err := h.readPostType(qs)
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	maxNodes := h.Utils.ReadIntQuery(qs, "nodes", 50)
+	if maxNodes < 2 || maxNodes > 150 {
+		h.Utils.BadRequest(c, fmt.Errorf("nodes must be between 2 and 150"))
+		return fmt.Errorf("invalid nodes")
+	}
+
+	minScore, err := strconv.ParseFloat(h.Utils.ReadStringQuery(qs, "min_score", strconv.FormatFloat(defaultMinEdgeScore, 'f', -1, 64)), 64)
+	if err != nil || minScore < 0 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid min_score"))
+		return fmt.Errorf("invalid min_score")
+	}
+
+	postTerms, err := h.Data.Posts.GetPostTerms(sub, postType, intervalInt)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting post terms %v", err)
+	}
+
+	docs := make([][]string, 0, len(postTerms))
+	for _, terms := range postTerms {
+		var tokens []string
+		for term, count := range terms {
+			for i := 0; i < count; i++ {
+				tokens = append(tokens, term)
+			}
+		}
+		docs = append(docs, tokens)
+	}
+
+	graph := buildWordGraph(docs, maxNodes, minScore)
+
+	// a graph doesn't fit in one table, the edges carry the nodes by name
+	if format != formatJSON {
+		return writeRows(c, format, fmt.Sprintf("%s_word_graph_edges", sub), graph.Edges)
+	}
+
+	return c.JSON(http.StatusOK, Cake{"graph": graph, "posts": len(docs)})
+}
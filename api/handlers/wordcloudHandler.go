@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/priyankishorems/bollytics-go/wordcloud"
+)
+
+const wordCloudDir = "public/wordcloud"
+
+func (h *Handlers) wordCloudWords(sub, interval, postType string) ([]wordcloud.Word, error) {
+	trendingWords, err := h.GetTrendingWordsHandler(sub, interval, postType)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]wordcloud.Word, 0, len(trendingWords))
+	for _, w := range trendingWords {
+		words = append(words, wordcloud.Word{Text: w.Word, Count: w.Count})
+	}
+
+	return words, nil
+}
+
+// WriteWordCloudPNG renders the monthly word cloud of a sub to
+// public/wordcloud/{sub}_wordcloud.png.
+func (h *Handlers) WriteWordCloudPNG(sub string) error {
+	words, err := h.wordCloudWords(sub, intervalMonth, "")
+	if err != nil {
+		return err
+	}
+
+	layout, err := wordcloud.Generate(words, wordcloud.DefaultOptions)
+	if err != nil {
+		return fmt.Errorf("error generating word cloud %v", err)
+	}
+
+	if err := os.MkdirAll(wordCloudDir, 0o755); err != nil {
+		return err
+	}
+
+	// write next to the old image and swap, so it is never served half written
+	path := filepath.Join(wordCloudDir, fmt.Sprintf("%s_wordcloud.png", sub))
+	tmp, err := os.CreateTemp(wordCloudDir, sub+"_*.png")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := layout.PNG(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error rendering word cloud %v", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (h *Handlers) GetWordCloudSVGHandler(c echo.Context) error {
+	sub, err := h.Utils.ReadStringParam(c, "sub")
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return fmt.Errorf("invalid sub %v", err)
+	}
+
+	if slices.Index(subReddits, sub) == -1 {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid sub"))
+		return fmt.Errorf("invalid sub")
+	}
+
+	interval := h.Utils.ReadStringQuery(c.QueryParams(), "interval", intervalMonth)
+	if interval != intervalWeek && interval != intervalMonth {
+		h.Utils.BadRequest(c, fmt.Errorf("invalid interval"))
+		return fmt.Errorf("invalid interval")
+	}
+
+	postType, err := h.readPostType(c.QueryParams())
+	if err != nil {
+		h.Utils.BadRequest(c, err)
+		return err
+	}
+
+	words, err := h.wordCloudWords(sub, interval, postType)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error getting trending words %v", err)
+	}
+
+	key := wordcloud.Hash(words, "svg")
+
+	if svg, ok := h.WordClouds.Get(key); ok {
+		return c.Blob(http.StatusOK, "image/svg+xml", svg)
+	}
+
+	layout, err := wordcloud.Generate(words, wordcloud.DefaultOptions)
+	if err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error generating word cloud %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := layout.SVG(&buf); err != nil {
+		h.Utils.InternalServerError(c, err)
+		return fmt.Errorf("error rendering word cloud %v", err)
+	}
+
+	h.WordClouds.Set(key, buf.Bytes())
+
+	return c.Blob(http.StatusOK, "image/svg+xml", buf.Bytes())
+}
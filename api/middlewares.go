@@ -1,9 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +13,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/pascaldekloe/jwt"
 	"github.com/priyankishorems/bollytics-go/api/handlers"
+	"github.com/priyankishorems/bollytics-go/internal/cache"
 	"github.com/priyankishorems/bollytics-go/utils"
 	"github.com/tomasen/realip"
 	"golang.org/x/time/rate"
@@ -18,6 +21,7 @@ import (
 
 var (
 	ErrUserUnauthorized = echo.NewHTTPError(http.StatusUnauthorized, "user unauthorized")
+	ErrUserForbidden    = echo.NewHTTPError(http.StatusForbidden, "user forbidden")
 )
 
 func Authenticate(h handlers.Handlers) echo.MiddlewareFunc {
@@ -115,6 +119,23 @@ func OptionalAuthenticate(h handlers.Handlers) echo.MiddlewareFunc {
 	}
 }
 
+// RequireAdmin only lets the configured admins through. It goes after
+// Authenticate, which sets who the user is.
+func RequireAdmin(h handlers.Handlers) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			reddit_uid, _ := c.Get("reddit_uid").(string)
+			if reddit_uid == "" || slices.Index(h.Config.Admins, reddit_uid) == -1 {
+				err := fmt.Errorf("user %q is not an admin", reddit_uid)
+				h.Utils.ForbiddenResponse(c, err)
+				return ErrUserForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
+
 func IPRateLimit(h *handlers.Handlers) echo.MiddlewareFunc {
 
 	type client struct {
@@ -172,3 +193,145 @@ func IPRateLimit(h *handlers.Handlers) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// responseMaxAge is how long clients may reuse a response before checking
+// back. The data only changes with the nightly ingestion, and revalidating
+// is cheap with the ETag.
+const responseMaxAge = 10 * time.Minute
+
+// CacheResponses serves successful GET responses from the in-process cache
+// and answers conditional requests with 304. The cache is emptied whenever
+// new data is committed.
+func CacheResponses(h *handlers.Handlers) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next(c)
+			}
+
+			// exports stream as they are read, holding them back would defeat that
+			if handlers.ExportFormat(c) != "json" {
+				return next(c)
+			}
+
+			res := c.Response()
+			res.Header().Add("Vary", "Accept")
+
+			key := cache.Key(req.URL.Path, c.QueryParams(), req.Header.Get("Accept"))
+			if entry, ok := h.Responses.Get(key); ok {
+				return writeCached(c, entry)
+			}
+
+			generation := h.Responses.Generation()
+
+			original := res.Writer
+			buffer := &bufferedWriter{header: res.Header().Clone()}
+			res.Writer = buffer
+
+			err := next(c)
+
+			res.Writer = original
+			for k, v := range buffer.header {
+				original.Header()[k] = v
+			}
+
+			if err != nil || buffer.status != http.StatusOK {
+				if buffer.status != 0 {
+					original.WriteHeader(buffer.status)
+				}
+				original.Write(buffer.body.Bytes())
+				return err
+			}
+
+			entry := cache.Entry{
+				ContentType:  buffer.header.Get(echo.HeaderContentType),
+				Body:         buffer.body.Bytes(),
+				ETag:         cache.ETag(buffer.body.Bytes()),
+				LastModified: buffer.header.Get(echo.HeaderLastModified),
+			}
+			h.Responses.Set(key, generation, entry)
+
+			// the buffered pass already marked the response as committed
+			res.Committed = false
+			res.Size = 0
+
+			return writeCached(c, entry)
+		}
+	}
+}
+
+func writeCached(c echo.Context, entry cache.Entry) error {
+	header := c.Response().Header()
+	header.Set("ETag", entry.ETag)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(responseMaxAge.Seconds())))
+
+	if entry.LastModified != "" {
+		header.Set(echo.HeaderLastModified, entry.LastModified)
+	}
+
+	if notModified(c.Request(), entry) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, entry.ContentType, entry.Body)
+}
+
+// notModified checks If-None-Match first and only falls back to
+// If-Modified-Since without it, as RFC 9110 has it.
+func notModified(req *http.Request, entry cache.Entry) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, entry.ETag)
+	}
+
+	ifModifiedSince := req.Header.Get(echo.HeaderIfModifiedSince)
+	if ifModifiedSince == "" || entry.LastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(entry.LastModified)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds on to a response until the cache has had a look at it.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
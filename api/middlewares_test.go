@@ -0,0 +1,27 @@
+package api
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"abc123"`
+
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"abc123"`, true},
+		{`W/"abc123"`, true},
+		{`"xyz", "abc123"`, true},
+		{`"xyz",W/"abc123"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`abc123`, false},
+		{`"abc1234"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
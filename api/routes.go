@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/go-co-op/gocron/v2"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -26,6 +28,11 @@ func SetupRoutes(h *handlers.Handlers) *echo.Echo {
 	e.GET("/refresh", h.RefreshTokenHandler, Authenticate(*h))
 	e.GET("/proxy/:url", h.ProxyHandler)
 
+	feeds := e.Group("/feeds")
+	{
+		feeds.GET("/:sub/:feed", h.GetFeedHandler, CacheResponses(h))
+	}
+
 	api := e.Group("/api")
 	{
 
@@ -56,6 +63,25 @@ func SetupRoutes(h *handlers.Handlers) *echo.Echo {
 			poll.DELETE("/delete/:poll_id", h.DeletePollByCreatorHandler, Authenticate(*h))
 		}
 
+		me := api.Group("/me")
+		{
+			me.GET("/watchlist", h.GetWatchlistsHandler, Authenticate(*h))
+			me.POST("/watchlist", h.CreateWatchlistHandler, Authenticate(*h))
+			me.GET("/watchlist/feed", h.GetWatchlistFeedHandler, Authenticate(*h))
+			me.POST("/watchlist/feed/read", h.MarkWatchlistFeedReadHandler, Authenticate(*h))
+			me.PUT("/watchlist/:id", h.UpdateWatchlistHandler, Authenticate(*h))
+			me.DELETE("/watchlist/:id", h.DeleteWatchlistHandler, Authenticate(*h))
+			me.GET("/recap/:year", h.GetUserRecapHandler, Authenticate(*h))
+		}
+
+		admin := api.Group("/admin")
+		{
+			admin.GET("/tracking", h.GetTrackingWindowsHandler, Authenticate(*h), RequireAdmin(*h))
+			admin.POST("/tracking", h.CreateTrackingWindowHandler, Authenticate(*h), RequireAdmin(*h))
+			admin.PUT("/tracking/:id", h.UpdateTrackingWindowHandler, Authenticate(*h), RequireAdmin(*h))
+			admin.DELETE("/tracking/:id", h.DeleteTrackingWindowHandler, Authenticate(*h), RequireAdmin(*h))
+		}
+
 		tmdb := api.Group("/tmdb")
 		{
 			tmdb.GET("/actors/:name", h.SearchActorsHandler)
@@ -64,14 +90,48 @@ func SetupRoutes(h *handlers.Handlers) *echo.Echo {
 
 		reddit := api.Group("/reddit")
 		{
+			cached := CacheResponses(h)
+
 			reddit.GET("/temp", h.GetFromReddit)
-			reddit.GET("/:sub/trending", h.GetTrendingWordsHandlerWeb)
-			reddit.GET("/:sub/frequency", h.GetPostFrequencyHandler)
-			reddit.GET("/:sub/:category/users", h.GetTopUsersHandler)
-			reddit.GET("/:sub/:category/posts", h.GetTopPostsHandler)
+			reddit.GET("/search", h.SearchPostsHandler, cached)
+			reddit.GET("/scoring", h.GetScoringProfilesHandler)
+			reddit.GET("/buzz/compare", h.GetBuzzCompareHandler, cached)
+			reddit.GET("/ratings/:tmdb_id", h.GetMovieRatingHandler, cached)
+			reddit.GET("/boxoffice/:tmdb_id", h.GetBoxOfficeHandler, cached)
+			reddit.GET("/tracking", h.GetTrackingWindowsHandler)
+			reddit.GET("/tracking/:id", h.GetTrackingVolumeHandler)
+			reddit.GET("/:sub/trending", h.GetTrendingWordsHandlerWeb, cached)
+			reddit.GET("/:sub/frequency", h.GetPostFrequencyHandler, cached)
+			reddit.GET("/:sub/events", h.GetEventsHandler, cached)
+			reddit.GET("/:sub/best-time", h.GetBestTimeHandler, cached)
+			reddit.GET("/:sub/wordcloud.svg", h.GetWordCloudSVGHandler, cached)
+			reddit.GET("/:sub/word-graph", h.GetWordGraphHandler, cached)
+			reddit.GET("/:sub/distributions", h.GetDistributionsHandler, cached)
+			reddit.GET("/:sub/types", h.GetPostTypesHandler, cached)
+			reddit.GET("/:sub/growth", h.GetGrowthHandler, cached)
+			reddit.GET("/:sub/stories", h.GetStoriesHandler, cached)
+			reddit.GET("/:sub/topics", h.GetTopicsHandler, cached)
+			reddit.GET("/:sub/distinctive", h.GetDistinctiveTermsHandler, cached)
+			reddit.GET("/:sub/export", h.ExportPostsHandler)
+			reddit.GET("/:sub/:category/users", h.GetTopUsersHandler, cached)
+			reddit.GET("/:sub/:category/posts", h.GetTopPostsHandler, cached)
 			// reddit.GET("/update", h.UpdatePostsFromRedditHandler)
 		}
 
+		digests := api.Group("/digests")
+		{
+			cached := CacheResponses(h)
+
+			digests.GET("/:sub", h.GetDigestHandler, cached)
+			digests.GET("/:sub/archive", h.GetDigestArchiveHandler, cached)
+			digests.GET("/:sub/:week", h.GetDigestHandler, cached)
+		}
+
+		recap := api.Group("/recap")
+		{
+			recap.GET("/:sub/:year", h.GetRecapHandler, CacheResponses(h))
+		}
+
 		scheduler, err := gocron.NewScheduler()
 		if err != nil {
 			log.Fatal("Error creating scheduler", err)
@@ -80,6 +140,13 @@ func SetupRoutes(h *handlers.Handlers) *echo.Echo {
 		updatePostsAtTimes := gocron.NewAtTimes(updatePostsAtTime)
 		updateWordCloudAtTime := gocron.NewAtTime(23, 55, 00)
 		updateWordCloudAtTimes := gocron.NewAtTimes(updateWordCloudAtTime)
+		weeklyDigestAtTime := gocron.NewAtTime(0, 10, 00)
+		weeklyDigestAtTimes := gocron.NewAtTimes(weeklyDigestAtTime)
+		monthlyTopicsAtTime := gocron.NewAtTime(0, 20, 00)
+		monthlyTopicsAtTimes := gocron.NewAtTimes(monthlyTopicsAtTime)
+		yearlyRecapAtTime := gocron.NewAtTime(0, 30, 00)
+		yearlyRecapAtTimes := gocron.NewAtTimes(yearlyRecapAtTime)
+		trackingPollInterval := 5 * time.Minute
 
 		updateRedditPostsJob, err := jobs.UpdateRedditPostsJob(*h, scheduler, updatePostsAtTimes)
 		if err != nil {
@@ -91,8 +158,32 @@ func SetupRoutes(h *handlers.Handlers) *echo.Echo {
 			log.Fatal("Error creating job: ", err)
 		}
 
+		weeklyDigestJob, err := jobs.WeeklyDigestJob(*h, scheduler, weeklyDigestAtTimes)
+		if err != nil {
+			log.Fatal("Error creating job: ", err)
+		}
+
+		monthlyTopicsJob, err := jobs.MonthlyTopicsJob(*h, scheduler, monthlyTopicsAtTimes)
+		if err != nil {
+			log.Fatal("Error creating job: ", err)
+		}
+
+		yearlyRecapJob, err := jobs.YearlyRecapJob(*h, scheduler, yearlyRecapAtTimes)
+		if err != nil {
+			log.Fatal("Error creating job: ", err)
+		}
+
+		pollTrackingWindowsJob, err := jobs.PollTrackingWindowsJob(*h, scheduler, trackingPollInterval)
+		if err != nil {
+			log.Fatal("Error creating job: ", err)
+		}
+
 		log.Info("updateRedditPostsJob started: ", updateRedditPostsJob.ID())
 		log.Info("updateWordCloudsJob started: ", updateWordCloudsJob.ID())
+		log.Info("weeklyDigestJob started: ", weeklyDigestJob.ID())
+		log.Info("monthlyTopicsJob started: ", monthlyTopicsJob.ID())
+		log.Info("yearlyRecapJob started: ", yearlyRecapJob.ID())
+		log.Info("pollTrackingWindowsJob started: ", pollTrackingWindowsJob.ID())
 
 		scheduler.Start()
 
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	tmdb "github.com/cyruzin/golang-tmdb"
@@ -10,8 +11,11 @@ import (
 	"github.com/labstack/gommon/log"
 	"github.com/priyankishorems/bollytics-go/api"
 	"github.com/priyankishorems/bollytics-go/api/handlers"
+	"github.com/priyankishorems/bollytics-go/internal/cache"
 	"github.com/priyankishorems/bollytics-go/internal/data"
+	"github.com/priyankishorems/bollytics-go/internal/minhash"
 	"github.com/priyankishorems/bollytics-go/utils"
+	"github.com/priyankishorems/bollytics-go/wordcloud"
 	sw "github.com/toadharvard/stopwords-iso"
 	"github.com/vartanbeno/go-reddit/v2/reddit"
 )
@@ -29,8 +33,30 @@ func main() {
 	flag.StringVar(&cfg.JWT.Secret, "jwt-secret", utils.JWTSecret, "JWT secret")
 	flag.StringVar(&cfg.JWT.Issuer, "jwt-issuer", utils.JWTIssuer, "JWT issuer")
 	flag.BoolVar(&cfg.RateLimiter.Enabled, "limiter-enabled", false, "Rate limiter enabled")
+	flag.StringVar(&cfg.ScoringProfiles, "scoring-profiles", "scoring.json", "Scoring profiles config file")
+	flag.StringVar(&cfg.PostTypeRules, "post-types", "post_types.json", "Post type rules config file")
+	flag.StringVar(&cfg.MovieAliases, "movie-aliases", "movie_aliases.json", "Movie aliases config file")
+	flag.StringVar(&cfg.PublicURL, "public-url", utils.PublicURL, "Public base url of the api, for the links in feeds")
+	admins := flag.String("admins", utils.AdminUIDs, "Comma separated reddit ids of the admins")
+	rebuildTerms := flag.Bool("rebuild-terms", false, "Rebuild the post terms of every stored post and exit")
+	reclassify := flag.Bool("reclassify", false, "Classify the type of every stored post again and exit")
+	buildTopics := flag.Int("build-topics", 0, "Build the topics of this many past months and exit")
+	buildRecap := flag.Int("build-recap", 0, "Build the recaps of this year and exit")
+	extractBoxOffice := flag.Bool("extract-box-office", false, "Extract the box office figures of every stored post again and exit")
 
 	flag.Parse()
+
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = fmt.Sprintf("http://localhost:%d", cfg.Port)
+	}
+	cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+
+	for _, id := range strings.Split(*admins, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.Admins = append(cfg.Admins, id)
+		}
+	}
+
 	log.SetHeader("${time_rfc3339} ${level}")
 
 	db := data.PSQLDB{}
@@ -77,6 +103,21 @@ func main() {
 
 	log.Info("Reddit client initialized")
 
+	scoring, err := data.LoadScoringProfiles(cfg.ScoringProfiles)
+	if err != nil {
+		log.Fatalf("error in loading scoring profiles; %v", err)
+	}
+
+	classifier, err := data.LoadPostClassifier(cfg.PostTypeRules)
+	if err != nil {
+		log.Fatalf("error in loading post type rules; %v", err)
+	}
+
+	movieAliases, err := data.LoadMovieAliases(cfg.MovieAliases)
+	if err != nil {
+		log.Fatalf("error in loading movie aliases; %v", err)
+	}
+
 	h := &handlers.Handlers{
 		Config:   *cfg,
 		Validate: validate,
@@ -84,8 +125,49 @@ func main() {
 		Data:     data.NewModel(dbPool),
 		Tmdb:     tmdbClient,
 		// RedditBot:      redditBot,
-		Reddit:   redditClient,
-		Stopword: stopword,
+		Reddit:       redditClient,
+		Stopword:     stopword,
+		WordClouds:   wordcloud.NewCache(),
+		Responses:    cache.New(1024),
+		Scoring:      scoring,
+		Classifier:   classifier,
+		Minhash:      minhash.New(minhash.DefaultOptions),
+		MovieAliases: movieAliases,
+	}
+
+	if *rebuildTerms {
+		if err := h.RebuildPostTerms(); err != nil {
+			log.Fatalf("error in rebuilding post terms; %v", err)
+		}
+		return
+	}
+
+	if *reclassify {
+		if err := h.ReclassifyPosts(); err != nil {
+			log.Fatalf("error in reclassifying posts; %v", err)
+		}
+		return
+	}
+
+	if *buildTopics > 0 {
+		if err := h.BuildTopics(*buildTopics); err != nil {
+			log.Fatalf("error in building topics; %v", err)
+		}
+		return
+	}
+
+	if *buildRecap > 0 {
+		if err := h.BuildRecaps(*buildRecap); err != nil {
+			log.Fatalf("error in building recaps; %v", err)
+		}
+		return
+	}
+
+	if *extractBoxOffice {
+		if err := h.ExtractBoxOffice(); err != nil {
+			log.Fatalf("error in extracting box office figures; %v", err)
+		}
+		return
 	}
 
 	e := api.SetupRoutes(h)
@@ -0,0 +1,283 @@
+// Package boxoffice reads the collections fans report in their posts, like
+// "150 Cr worldwide day 4" or "TN gross 45.5 crores", with the amounts in
+// crore.
+package boxoffice
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	RegionWorldwide   = "worldwide"
+	RegionIndia       = "india"
+	RegionOverseas    = "overseas"
+	RegionUnspecified = "unspecified"
+
+	KindGross       = "gross"
+	KindNett        = "nett"
+	KindShare       = "share"
+	KindUnspecified = "unspecified"
+
+	// BasisTotal is the collection so far, BasisDay what a single day made.
+	BasisTotal = "total"
+	BasisDay   = "day"
+)
+
+// maxCrore is past anything a film has made, a bigger figure is a typo or
+// not a collection.
+const maxCrore = 5000
+
+// Figure is a collection reported in a post. Day is the day since release it
+// is reported for, 0 when the post doesn't say.
+type Figure struct {
+	Crore  float64 `json:"crore"`
+	Region string  `json:"region"`
+	Kind   string  `json:"kind"`
+	Basis  string  `json:"basis"`
+	Day    int     `json:"day"`
+	Raw    string  `json:"raw"`
+}
+
+type keyword struct {
+	value   string
+	pattern *regexp.Regexp
+}
+
+func keywords(values map[string][]string, order []string) []keyword {
+	var list []keyword
+	for _, value := range order {
+		words := make([]string, len(values[value]))
+		for i, w := range values[value] {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		list = append(list, keyword{value, regexp.MustCompile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)})
+	}
+	return list
+}
+
+var (
+	amount = regexp.MustCompile(`(?i)(?:(?:₹|rs\.?|inr)\s*)?(?:(\d+(?:\.\d+)?)\s*(?:-|–|to)\s*)?(\d{1,3}(?:,\d{2,3})+(?:\.\d+)?|\d+(?:\.\d+)?)\s*(k\s*)?(crores?|crs?|lakhs?|lacs?|l)\b`)
+
+	dayPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bday\s*[-#:]?\s*(\d{1,3})\b`),
+		regexp.MustCompile(`(?i)\bd(\d{1,3})\b`),
+		regexp.MustCompile(`(?i)\b(\d{1,3})(?:st|nd|rd|th)\s+day\b`),
+		regexp.MustCompile(`(?i)\b(opening|first)\s+day\b`),
+	}
+
+	regions = keywords(map[string][]string{
+		RegionWorldwide: {"worldwide", "world wide", "ww", "wwbo", "global", "globally"},
+		RegionOverseas:  {"overseas", "os", "international"},
+		RegionIndia:     {"all india", "india", "indian", "ind", "domestic"},
+		"tamil_nadu":    {"tn", "tamil nadu", "tamilnadu"},
+		"kerala":        {"kerala", "kl"},
+		"karnataka":     {"karnataka", "ka", "ktk"},
+		"ap_tg":         {"ap/tg", "ap/ts", "apts", "aptg", "ap & tg", "ap tg", "telugu states", "ap", "tg", "nizam"},
+	}, []string{RegionWorldwide, RegionOverseas, RegionIndia, "tamil_nadu", "kerala", "karnataka", "ap_tg"})
+
+	kinds = keywords(map[string][]string{
+		KindGross: {"gross", "grs"},
+		KindNett:  {"nett", "net"},
+		KindShare: {"share", "shares"},
+	}, []string{KindGross, KindNett, KindShare})
+
+	dailyWords = regexp.MustCompile(`(?i)\b(?:alone|daily|on day|single day)\b`)
+
+	// amounts next to these aren't collections
+	notCollections = regexp.MustCompile(`(?i)\b(?:budget|salary|remuneration|fee|fees|rights|deal|ott|satellite|business|loan|loss|likes|views|followers|subscribers|tickets|footfalls|shows)\b`)
+
+	// a comma only ends a clause when a space follows, 1,234 is a number
+	separators = regexp.MustCompile(`;|,\s+`)
+)
+
+// nearest returns the value of the keyword closest to [start, end) in text,
+// a keyword before it winning a tie.
+func nearest(list []keyword, text string, start, end int) (string, bool) {
+	best, bestDistance := "", math.MaxInt
+	for _, k := range list {
+		for _, m := range k.pattern.FindAllStringIndex(text, -1) {
+			var distance int
+			switch {
+			case m[1] <= start:
+				distance = start - m[1]
+			case m[0] >= end:
+				distance = m[0] - end + 1
+			default:
+				continue
+			}
+			if distance < bestDistance {
+				best, bestDistance = k.value, distance
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// findDay returns the day the text reports, the closest mention to pos.
+func findDay(text string, pos int) int {
+	day, bestDistance := 0, math.MaxInt
+	for _, pattern := range dayPatterns {
+		for _, m := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			distance := pos - m[1]
+			if m[0] >= pos {
+				distance = m[0] - pos
+			}
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance >= bestDistance {
+				continue
+			}
+
+			value := text[m[2]:m[3]]
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				// opening or first day
+				n = 1
+			}
+			if n < 1 {
+				continue
+			}
+			day, bestDistance = n, distance
+		}
+	}
+	return day
+}
+
+func parseNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	return v, err == nil
+}
+
+// crore reads the amount of a match in crore.
+func crore(text string, m []int) (float64, bool) {
+	value, ok := parseNumber(text[m[4]:m[5]])
+	if !ok {
+		return 0, false
+	}
+
+	if m[2] != -1 {
+		low, ok := parseNumber(text[m[2]:m[3]])
+		if !ok || low > value {
+			return 0, false
+		}
+		value = (low + value) / 2
+	}
+
+	if m[6] != -1 {
+		value *= 1000
+	}
+
+	unit := strings.ToLower(text[m[8]:m[9]])
+	if unit == "l" && m[8] > m[5] {
+		// a lone L only means lakh stuck to the number, as in 50L
+		return 0, false
+	}
+	if strings.HasPrefix(unit, "l") {
+		value /= 100
+	}
+
+	if value <= 0 || value > maxCrore {
+		return 0, false
+	}
+
+	return math.Round(value*10000) / 10000, true
+}
+
+func extract(text, title string) []Figure {
+	var figures []Figure
+
+	for _, line := range strings.Split(text, "\n") {
+		bounds := [][]int{{0, 0}}
+		bounds = append(bounds, separators.FindAllStringIndex(line, -1)...)
+		bounds = append(bounds, []int{len(line), len(line)})
+
+		for b := 1; b < len(bounds); b++ {
+			offset := bounds[b-1][1]
+			segment := line[offset:bounds[b][0]]
+			matches := amount.FindAllStringSubmatchIndex(segment, -1)
+
+			for i, m := range matches {
+				value, ok := crore(segment, m)
+				if !ok {
+					continue
+				}
+
+				// the words between the amounts on either side belong to this one
+				from, to := 0, len(segment)
+				if i > 0 {
+					from = matches[i-1][1]
+				}
+				if i+1 < len(matches) {
+					to = matches[i+1][0]
+				}
+				context := segment[from:to]
+				start, end := m[0]-from, m[1]-from
+
+				if notCollections.MatchString(context) {
+					continue
+				}
+
+				figure := Figure{
+					Crore:  value,
+					Region: RegionUnspecified,
+					Kind:   KindUnspecified,
+					Basis:  BasisTotal,
+					Raw:    strings.Join(strings.Fields(segment[m[0]:m[1]]), " "),
+				}
+
+				if region, ok := nearest(regions, context, start, end); ok {
+					figure.Region = region
+				}
+				if kind, ok := nearest(kinds, context, start, end); ok {
+					figure.Kind = kind
+				}
+				if dailyWords.MatchString(context) {
+					figure.Basis = BasisDay
+				}
+
+				figure.Day = findDay(context, start)
+				if figure.Day == 0 {
+					figure.Day = findDay(line, offset+m[0])
+				}
+				if figure.Day == 0 && title != "" {
+					figure.Day = findDay(title, len(title))
+				}
+
+				figures = append(figures, figure)
+			}
+		}
+	}
+
+	return figures
+}
+
+// Extract reads the figures of a post. The title sets the day for figures in
+// the body that don't say which day they are for, as in a "Day 4 collections"
+// post listing one region per line. A figure reported more than once in the
+// post is kept once.
+func Extract(title, body string) []Figure {
+	figures := append(extract(title, ""), extract(body, title)...)
+
+	type key struct {
+		crore               float64
+		region, kind, basis string
+		day                 int
+	}
+
+	seen := make(map[key]bool)
+	unique := figures[:0]
+	for _, f := range figures {
+		k := key{f.Crore, f.Region, f.Kind, f.Basis, f.Day}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, f)
+	}
+
+	return unique
+}
@@ -0,0 +1,92 @@
+package boxoffice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		want  []Figure
+	}{
+		{
+			name:  "worldwide total",
+			title: "Jailer crosses 600 Cr worldwide",
+			want:  []Figure{{Crore: 600, Region: RegionWorldwide, Kind: KindUnspecified, Basis: BasisTotal, Raw: "600 Cr"}},
+		},
+		{
+			name:  "region, kind and day",
+			title: "Jawan day 1: 75 Cr nett India",
+			want:  []Figure{{Crore: 75, Region: RegionIndia, Kind: KindNett, Basis: BasisTotal, Day: 1, Raw: "75 Cr"}},
+		},
+		{
+			name:  "title sets the day of the body",
+			title: "Leo Day 4 collections",
+			body:  "TN gross 45.5 crores\nKerala 5.2 cr\nOverseas 120 cr gross",
+			want: []Figure{
+				{Crore: 45.5, Region: "tamil_nadu", Kind: KindGross, Basis: BasisTotal, Day: 4, Raw: "45.5 crores"},
+				{Crore: 5.2, Region: "kerala", Kind: KindUnspecified, Basis: BasisTotal, Day: 4, Raw: "5.2 cr"},
+				{Crore: 120, Region: RegionOverseas, Kind: KindGross, Basis: BasisTotal, Day: 4, Raw: "120 cr"},
+			},
+		},
+		{
+			name:  "lakh stuck to the number",
+			title: "Animal 50L in Kerala on day 3",
+			want:  []Figure{{Crore: 0.5, Region: "kerala", Kind: KindUnspecified, Basis: BasisDay, Day: 3, Raw: "50L"}},
+		},
+		{
+			name:  "single day share",
+			title: "Salaar AP/TG share 10 cr on day 5 alone",
+			want:  []Figure{{Crore: 10, Region: "ap_tg", Kind: KindShare, Basis: BasisDay, Day: 5, Raw: "10 cr"}},
+		},
+		{
+			name:  "budget is left out",
+			title: "Budget of 300 Cr, made 1,050 Cr ww gross",
+			want:  []Figure{{Crore: 1050, Region: RegionWorldwide, Kind: KindGross, Basis: BasisTotal, Raw: "1,050 Cr"}},
+		},
+		{
+			name:  "thousand crore",
+			title: "Opening day 1.2k crore worldwide",
+			want:  []Figure{{Crore: 1200, Region: RegionWorldwide, Kind: KindUnspecified, Basis: BasisTotal, Day: 1, Raw: "1.2k crore"}},
+		},
+		{
+			name:  "range is averaged",
+			title: "Pathaan 55-60 Cr nett day 1",
+			want:  []Figure{{Crore: 57.5, Region: RegionUnspecified, Kind: KindNett, Basis: BasisTotal, Day: 1, Raw: "55-60 Cr"}},
+		},
+		{
+			name:  "rupee sign and ordinal day",
+			title: "₹150 crore in 3rd day",
+			want:  []Figure{{Crore: 150, Region: RegionUnspecified, Kind: KindUnspecified, Basis: BasisTotal, Day: 3, Raw: "₹150 crore"}},
+		},
+		{
+			name:  "repeated figure is kept once",
+			title: "Leo 100 cr WW",
+			body:  "Leo 100 cr WW",
+			want:  []Figure{{Crore: 100, Region: RegionWorldwide, Kind: KindUnspecified, Basis: BasisTotal, Raw: "100 cr"}},
+		},
+		{
+			name:  "past anything a film has made",
+			title: "Fighter 5500 cr",
+		},
+		{
+			name:  "not money",
+			title: "2.5M views on the trailer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.title, tt.body)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q, %q) = %+v, want %+v", tt.title, tt.body, got, tt.want)
+			}
+		})
+	}
+}
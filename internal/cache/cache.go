@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a rendered response, ready to be written again as is.
+type Entry struct {
+	ContentType  string
+	Body         []byte
+	ETag         string
+	LastModified string
+}
+
+// Cache keeps rendered responses in memory. Every entry belongs to the
+// generation it was computed in, and bumping the generation once new data is
+// committed throws all of them away at once. Once it is full, the least
+// recently used entry makes room for a new one.
+type Cache struct {
+	mu         sync.Mutex
+	generation uint64
+	// modified is when the generation started.
+	modified   time.Time
+	maxEntries int
+	entries    map[string]*list.Element
+	// recent orders the entries from the most to the least recently used.
+	recent *list.List
+}
+
+type item struct {
+	key   string
+	entry Entry
+}
+
+func New(maxEntries int) *Cache {
+	return &Cache{
+		modified:   time.Now(),
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+}
+
+func (c *Cache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// Modified is when the data behind the cached responses last changed, as far
+// as the cache knows. A restart counts as a change.
+func (c *Cache) Modified() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.modified
+}
+
+// Bump starts a new generation. Call it after the data behind the cached
+// responses has changed.
+func (c *Cache) Bump() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.modified = time.Now()
+	c.entries = make(map[string]*list.Element)
+	c.recent.Init()
+}
+
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+
+	c.recent.MoveToFront(el)
+	return el.Value.(*item).entry, true
+}
+
+// Set stores the entry if the generation it was computed in is still the
+// current one, so a request that raced an ingestion can't put stale data back.
+func (c *Cache) Set(key string, generation uint64, e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*item).entry = e
+		c.recent.MoveToFront(el)
+		return
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*item).key)
+	}
+	c.entries[key] = c.recent.PushFront(&item{key: key, entry: e})
+}
+
+// Key identifies a request by its path, its query parameters in a stable
+// order and the representation it asked for.
+func Key(path string, query url.Values, accept string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(path)
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			b.WriteString("&")
+			b.WriteString(url.QueryEscape(k))
+			b.WriteString("=")
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(accept)
+
+	return b.String()
+}
+
+// ETag is a strong validator made from the body, so a response that comes out
+// the same after an ingestion still answers 304.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
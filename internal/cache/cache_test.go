@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name             string
+		a, b             url.Values
+		same             bool
+		acceptA, acceptB string
+	}{
+		{
+			name: "parameter order doesn't matter",
+			a:    url.Values{"sub": {"kollywood"}, "page": {"2"}},
+			b:    url.Values{"page": {"2"}, "sub": {"kollywood"}},
+			same: true,
+		},
+		{
+			name: "value order doesn't matter",
+			a:    url.Values{"against": {"bollywood", "tollywood"}},
+			b:    url.Values{"against": {"tollywood", "bollywood"}},
+			same: true,
+		},
+		{
+			name: "values differ",
+			a:    url.Values{"page": {"1"}},
+			b:    url.Values{"page": {"2"}},
+		},
+		{
+			name: "escaped separators can't collide",
+			a:    url.Values{"q": {"a&b=c"}},
+			b:    url.Values{"q": {"a"}, "b": {"c"}},
+		},
+		{
+			name:    "representations differ",
+			a:       url.Values{"page": {"1"}},
+			b:       url.Values{"page": {"1"}},
+			acceptA: "application/json",
+			acceptB: "text/csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Key("/api/reddit/kollywood/top", tt.a, tt.acceptA)
+			b := Key("/api/reddit/kollywood/top", tt.b, tt.acceptB)
+			if (a == b) != tt.same {
+				t.Errorf("Key() = %q and %q, want same = %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestSetEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(2)
+	gen := c.Generation()
+
+	c.Set("a", gen, Entry{Body: []byte("a")})
+	c.Set("b", gen, Entry{Body: []byte("b")})
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) missed")
+	}
+
+	c.Set("c", gen, Entry{Body: []byte("c")})
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%s) ok = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestSetSkipsStaleGeneration(t *testing.T) {
+	c := New(2)
+	gen := c.Generation()
+
+	c.Set("a", gen, Entry{Body: []byte("a")})
+	c.Bump()
+	c.Set("b", gen, Entry{Body: []byte("b")})
+
+	for _, key := range []string{"a", "b"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("Get(%s) hit after Bump", key)
+		}
+	}
+}
+
+func TestBumpMovesModified(t *testing.T) {
+	c := New(2)
+	before := c.Modified()
+
+	time.Sleep(time.Millisecond)
+	c.Bump()
+
+	if !c.Modified().After(before) {
+		t.Errorf("Modified() = %v after Bump, want after %v", c.Modified(), before)
+	}
+}
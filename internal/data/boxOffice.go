@@ -0,0 +1,134 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	pg "github.com/jackc/pgx/v5"
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+	"github.com/priyankishorems/bollytics-go/internal/boxoffice"
+)
+
+type BoxOfficeModel struct {
+	DB *pgx.Pool
+}
+
+// BoxOfficeClaim is a figure as a post reported it.
+type BoxOfficeClaim struct {
+	Crore      float64   `json:"crore"`
+	Region     string    `json:"region"`
+	Kind       string    `json:"kind"`
+	Basis      string    `json:"basis"`
+	Day        int       `json:"day"`
+	Inferred   bool      `json:"inferred"`
+	Raw        string    `json:"raw"`
+	PostID     string    `json:"post_id"`
+	Subreddit  string    `json:"subreddit"`
+	Title      string    `json:"title"`
+	Author     string    `json:"author"`
+	URL        string    `json:"url"`
+	Upvotes    int       `json:"upvotes"`
+	CreatedUTC time.Time `json:"created_utc"`
+}
+
+// ReplaceFigures swaps the stored figures of the posts for these. A post
+// with no figures has its old ones removed.
+func (b BoxOfficeModel) ReplaceFigures(figures map[string][]boxoffice.Figure) (err error) {
+	if len(figures) == 0 {
+		return nil
+	}
+
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	tx, err := b.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", r)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	ids := make([]string, 0, len(figures))
+	var rows [][]any
+	for id, postFigures := range figures {
+		ids = append(ids, id)
+		for i, f := range postFigures {
+			var day *int
+			if f.Day > 0 {
+				day = &f.Day
+			}
+			rows = append(rows, []any{id, i, f.Crore, f.Region, f.Kind, f.Basis, day, f.Raw})
+		}
+	}
+
+	if _, err = tx.Exec(ctx, DeleteBoxOfficeFiguresQuery, ids); err != nil {
+		err = fmt.Errorf("error in deleting box office figures; %v", err)
+		return
+	}
+
+	_, err = tx.CopyFrom(ctx, pg.Identifier{"box_office_figures"}, []string{"post_id", "position", "crore", "region", "kind", "basis", "day", "raw"}, pg.CopyFromRows(rows))
+	if err != nil {
+		err = fmt.Errorf("error in inserting box office figures; %v", err)
+		return
+	}
+
+	return nil
+}
+
+// DeleteStaleFigures drops the figures of posts that aren't box office posts
+// anymore.
+func (b BoxOfficeModel) DeleteStaleFigures() (int64, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DeleteStaleBoxOfficeFiguresQuery
+
+	tag, err := b.DB.Exec(ctx, query, PostTypeBoxOffice)
+	if err != nil {
+		return 0, fmt.Errorf("error in deleting stale box office figures; %v", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetMovieClaims returns the figures of the posts of the subs created in
+// [from, to) with any of the names in the title, oldest first. An empty
+// region or kind means any.
+func (b BoxOfficeModel) GetMovieClaims(subs, names []string, from, to time.Time, region, kind string) ([]BoxOfficeClaim, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := MovieBoxOfficeQuery
+
+	rows, err := b.DB.Query(ctx, query, subs, from.UTC(), to.UTC(), names, region, kind)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting box office claims; %v", err)
+	}
+	defer rows.Close()
+
+	var claims []BoxOfficeClaim
+	for rows.Next() {
+		var c BoxOfficeClaim
+		err = rows.Scan(&c.Crore, &c.Region, &c.Kind, &c.Basis, &c.Day, &c.Raw, &c.PostID, &c.Subreddit, &c.Title, &c.Author, &c.URL, &c.Upvotes, &c.CreatedUTC)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning box office claims; %v", err)
+		}
+		claims = append(claims, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading box office claims; %v", err)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,46 @@
+package data
+
+const (
+	DeleteBoxOfficeFiguresQuery = `
+	DELETE FROM box_office_figures
+	WHERE post_id = ANY($1::text[])
+	`
+
+	// DeleteStaleBoxOfficeFiguresQuery drops the figures of posts that are
+	// no longer box office posts after a reclassify.
+	DeleteStaleBoxOfficeFiguresQuery = `
+	DELETE FROM box_office_figures f
+	USING subreddit_posts p
+	WHERE p.id = f.post_id
+		AND p.post_type <> $1
+	`
+
+	MovieBoxOfficeQuery = `
+	SELECT f.crore,
+		f.region,
+		f.kind,
+		f.basis,
+		COALESCE(f.day, 0) AS day,
+		f.raw,
+		p.id,
+		p.subreddit,
+		p.title,
+		p.author,
+		p.permalink,
+		p.score,
+		p.created_utc
+	FROM box_office_figures f
+	JOIN subreddit_posts p ON p.id = f.post_id
+	WHERE p.subreddit = ANY($1::text[])
+		AND p.created_utc >= $2
+		AND p.created_utc < $3
+		AND ($5::text = '' OR f.region = $5)
+		AND ($6::text = '' OR f.kind = $6)
+		AND EXISTS (
+			SELECT 1
+			FROM unnest($4::text[]) AS alias
+			WHERE ts_filter(p.search_vector, '{a}') @@ phraseto_tsquery('english', alias)
+		)
+	ORDER BY p.created_utc ASC, f.position ASC
+	`
+)
@@ -0,0 +1,106 @@
+package data
+
+const (
+	UpsertDigestQuery = `
+	INSERT INTO digests (
+		subreddit,
+		week,
+		period_start,
+		period_end,
+		version,
+		document,
+		markdown,
+		html
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (subreddit, week) DO
+	UPDATE
+	SET
+		period_start = EXCLUDED.period_start,
+		period_end = EXCLUDED.period_end,
+		version = EXCLUDED.version,
+		document = EXCLUDED.document,
+		markdown = EXCLUDED.markdown,
+		html = EXCLUDED.html,
+		created_at = NOW()
+	`
+
+	LatestDigestQuery = `
+	SELECT id,
+		subreddit,
+		week,
+		period_start,
+		period_end,
+		version,
+		document,
+		markdown,
+		html,
+		created_at
+	FROM digests
+	WHERE subreddit = $1
+	ORDER BY period_start DESC
+	LIMIT 1
+	`
+
+	DigestByWeekQuery = `
+	SELECT id,
+		subreddit,
+		week,
+		period_start,
+		period_end,
+		version,
+		document,
+		markdown,
+		html,
+		created_at
+	FROM digests
+	WHERE subreddit = $1
+		AND week = $2
+	`
+
+	DigestArchiveQuery = `
+	SELECT COUNT(*) OVER () AS total,
+		subreddit,
+		week,
+		period_start,
+		period_end,
+		version,
+		created_at
+	FROM digests
+	WHERE subreddit = $1
+	ORDER BY period_start DESC
+	LIMIT $2 OFFSET $3
+	`
+
+	MostActivePollsQuery = `
+	SELECT p.id,
+		p.title,
+		(SELECT COUNT(*) FROM poll_votes pv WHERE pv.poll_id = p.id) AS total_votes,
+		COUNT(v.reddit_uid) AS period_votes
+	FROM polls p
+	LEFT JOIN poll_votes v ON v.poll_id = p.id
+		AND v.created_at >= $2
+		AND v.created_at < $3
+	WHERE p.subreddit = $1
+		AND p.start_time < $3
+		AND p.end_time >= $2
+	GROUP BY p.id, p.title
+	HAVING COUNT(v.reddit_uid) > 0
+	ORDER BY period_votes DESC
+	LIMIT $4
+	`
+
+	NewTierlistsQuery = `
+	SELECT tl.id,
+		tl.title,
+		u.username,
+		tl.created_at
+	FROM tierlists tl
+	INNER JOIN users u ON tl.reddit_uid = u.reddit_uid
+	WHERE tl.subreddit = $1
+		AND tl.created_at >= $2
+		AND tl.created_at < $3
+	ORDER BY tl.created_at DESC
+	LIMIT $4
+	`
+)
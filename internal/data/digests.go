@@ -0,0 +1,179 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pg "github.com/jackc/pgx/v5"
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDigestNotFound = errors.New("digest not found")
+
+type DigestsModel struct {
+	DB *pgx.Pool
+}
+
+type Digest struct {
+	ID          int             `json:"id"`
+	Subreddit   string          `json:"subreddit"`
+	Week        string          `json:"week"`
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	Version     int             `json:"version"`
+	Document    json.RawMessage `json:"document"`
+	Markdown    string          `json:"-"`
+	HTML        string          `json:"-"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type DigestSummary struct {
+	Subreddit   string    `json:"subreddit"`
+	Week        string    `json:"week"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ActivePoll struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	TotalVotes  int    `json:"total_votes"`
+	PeriodVotes int    `json:"period_votes"`
+}
+
+type NewTierlist struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (d DigestsModel) UpsertDigest(digest Digest) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UpsertDigestQuery
+
+	_, err := d.DB.Exec(ctx, query, digest.Subreddit, digest.Week, digest.PeriodStart.UTC(), digest.PeriodEnd.UTC(), digest.Version, digest.Document, digest.Markdown, digest.HTML)
+	if err != nil {
+		return fmt.Errorf("error in upserting digest; %v", err)
+	}
+
+	return nil
+}
+
+func (d DigestsModel) scanDigest(row pg.Row) (*Digest, error) {
+	var digest Digest
+	err := row.Scan(&digest.ID, &digest.Subreddit, &digest.Week, &digest.PeriodStart, &digest.PeriodEnd, &digest.Version, &digest.Document, &digest.Markdown, &digest.HTML, &digest.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, ErrDigestNotFound
+		}
+		return nil, fmt.Errorf("error in getting digest; %v", err)
+	}
+
+	return &digest, nil
+}
+
+func (d DigestsModel) GetLatestDigest(sub string) (*Digest, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := LatestDigestQuery
+
+	return d.scanDigest(d.DB.QueryRow(ctx, query, sub))
+}
+
+func (d DigestsModel) GetDigestByWeek(sub, week string) (*Digest, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DigestByWeekQuery
+
+	return d.scanDigest(d.DB.QueryRow(ctx, query, sub, week))
+}
+
+func (d DigestsModel) GetDigestArchive(sub string, filters Filters) ([]DigestSummary, Metadata, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DigestArchiveQuery
+
+	rows, err := d.DB.Query(ctx, query, sub, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in getting digest archive; %v", err)
+	}
+	defer rows.Close()
+
+	digests := []DigestSummary{}
+	totalRecords := 0
+	for rows.Next() {
+		var digest DigestSummary
+		err = rows.Scan(&totalRecords, &digest.Subreddit, &digest.Week, &digest.PeriodStart, &digest.PeriodEnd, &digest.Version, &digest.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error in scanning digest archive; %v", err)
+		}
+		digests = append(digests, digest)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return digests, metadata, nil
+}
+
+// GetMostActivePolls ranks the polls of the sub by the votes they got in the period.
+func (d DigestsModel) GetMostActivePolls(sub string, from, to time.Time, limit int) ([]ActivePoll, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := MostActivePollsQuery
+
+	rows, err := d.DB.Query(ctx, query, sub, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting active polls; %v", err)
+	}
+	defer rows.Close()
+
+	polls := []ActivePoll{}
+	for rows.Next() {
+		var poll ActivePoll
+		err = rows.Scan(&poll.ID, &poll.Title, &poll.TotalVotes, &poll.PeriodVotes)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning active polls; %v", err)
+		}
+		polls = append(polls, poll)
+	}
+
+	return polls, nil
+}
+
+// GetNewTierlists returns the tierlists made in the period. Tierlists have no
+// votes to rank them by, so the newest come first.
+func (d DigestsModel) GetNewTierlists(sub string, from, to time.Time, limit int) ([]NewTierlist, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := NewTierlistsQuery
+
+	rows, err := d.DB.Query(ctx, query, sub, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting new tierlists; %v", err)
+	}
+	defer rows.Close()
+
+	tierlists := []NewTierlist{}
+	for rows.Next() {
+		var tierlist NewTierlist
+		err = rows.Scan(&tierlist.ID, &tierlist.Title, &tierlist.Username, &tierlist.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning new tierlists; %v", err)
+		}
+		tierlists = append(tierlists, tierlist)
+	}
+
+	return tierlists, nil
+}
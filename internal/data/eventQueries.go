@@ -0,0 +1,88 @@
+package data
+
+const (
+	// ActivityQuery buckets the sub's posts of the last $2 days into $3 hour
+	// buckets, leaving out the bucket still in progress.
+	ActivityQuery = `
+	WITH buckets AS (
+		SELECT generate_series(
+			date_bin(make_interval(hours := $3::int), (now() AT TIME ZONE 'UTC') - make_interval(days := $2::int), timestamp '2000-01-01'),
+			date_bin(make_interval(hours := $3::int), now() AT TIME ZONE 'UTC', timestamp '2000-01-01') - make_interval(hours := $3::int),
+			make_interval(hours := $3::int)
+		) AS bucket
+	)
+	SELECT b.bucket,
+		COUNT(p.id) AS post_count,
+		COALESCE(SUM(p.score), 0) AS score_total
+	FROM buckets b
+	LEFT JOIN subreddit_posts p ON p.subreddit = $1
+		AND p.created_utc >= b.bucket
+		AND p.created_utc < b.bucket + make_interval(hours := $3::int)
+	GROUP BY b.bucket
+	ORDER BY b.bucket ASC
+	`
+
+	TopPostsInBucketQuery = `
+	SELECT id,
+		title,
+		author,
+		permalink,
+		score,
+		num_comments
+	FROM subreddit_posts
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+	ORDER BY score DESC
+	LIMIT $4
+	`
+
+	UpsertEventQuery = `
+	INSERT INTO subreddit_events (
+		subreddit,
+		bucket_start,
+		post_count,
+		score_total,
+		baseline_count,
+		baseline_score,
+		count_z,
+		score_z,
+		top_posts,
+		trending_terms
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (subreddit, bucket_start) DO
+	UPDATE
+	SET
+		post_count = EXCLUDED.post_count,
+		score_total = EXCLUDED.score_total,
+		baseline_count = EXCLUDED.baseline_count,
+		baseline_score = EXCLUDED.baseline_score,
+		count_z = EXCLUDED.count_z,
+		score_z = EXCLUDED.score_z,
+		top_posts = EXCLUDED.top_posts,
+		trending_terms = EXCLUDED.trending_terms,
+		detected_at = NOW()
+	`
+
+	GetEventsQuery = `
+	SELECT COUNT(*) OVER () AS total,
+		id,
+		subreddit,
+		bucket_start,
+		post_count,
+		score_total,
+		baseline_count,
+		baseline_score,
+		count_z,
+		score_z,
+		top_posts,
+		trending_terms,
+		detected_at
+	FROM subreddit_events
+	WHERE subreddit = $1
+	ORDER BY bucket_start DESC
+	LIMIT $2
+	OFFSET $3
+	`
+)
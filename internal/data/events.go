@@ -0,0 +1,153 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EventsModel struct {
+	DB *pgx.Pool
+}
+
+type BucketActivity struct {
+	Bucket     time.Time
+	PostCount  int
+	ScoreTotal int
+}
+
+type EventPost struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	URL         string `json:"url"`
+	Upvotes     int    `json:"upvotes"`
+	NumComments int    `json:"num_comments"`
+}
+
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type ActivityEvent struct {
+	ID            int         `json:"id"`
+	Subreddit     string      `json:"subreddit"`
+	BucketStart   time.Time   `json:"bucket_start"`
+	PostCount     int         `json:"post_count"`
+	ScoreTotal    int         `json:"score_total"`
+	BaselineCount float64     `json:"baseline_count"`
+	BaselineScore float64     `json:"baseline_score"`
+	CountZ        float64     `json:"count_z"`
+	ScoreZ        float64     `json:"score_z"`
+	TopPosts      []EventPost `json:"top_posts"`
+	TrendingTerms []TermCount `json:"trending_terms"`
+	DetectedAt    time.Time   `json:"detected_at"`
+}
+
+// GetActivity returns the sub's post count and score in buckets of the given
+// number of hours, over the last days.
+func (e EventsModel) GetActivity(sub string, days, bucketHours int) ([]BucketActivity, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := ActivityQuery
+
+	rows, err := e.DB.Query(ctx, query, sub, days, bucketHours)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting activity; %v", err)
+	}
+	defer rows.Close()
+
+	var activity []BucketActivity
+	for rows.Next() {
+		var bucket BucketActivity
+		err = rows.Scan(&bucket.Bucket, &bucket.PostCount, &bucket.ScoreTotal)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning activity; %v", err)
+		}
+		activity = append(activity, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading activity; %v", err)
+	}
+
+	return activity, nil
+}
+
+func (e EventsModel) GetTopPostsInBucket(sub string, start, end time.Time, limit int) ([]EventPost, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := TopPostsInBucketQuery
+
+	rows, err := e.DB.Query(ctx, query, sub, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting posts of bucket; %v", err)
+	}
+	defer rows.Close()
+
+	posts := []EventPost{}
+	for rows.Next() {
+		var post EventPost
+		err = rows.Scan(&post.ID, &post.Title, &post.Author, &post.URL, &post.Upvotes, &post.NumComments)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning posts of bucket; %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading posts of bucket; %v", err)
+	}
+
+	return posts, nil
+}
+
+func (e EventsModel) UpsertEvent(event ActivityEvent) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UpsertEventQuery
+
+	_, err := e.DB.Exec(ctx, query, event.Subreddit, event.BucketStart, event.PostCount, event.ScoreTotal, event.BaselineCount, event.BaselineScore, event.CountZ, event.ScoreZ, event.TopPosts, event.TrendingTerms)
+	if err != nil {
+		return fmt.Errorf("error in inserting event; %v", err)
+	}
+
+	return nil
+}
+
+func (e EventsModel) GetEvents(sub string, filters Filters) ([]ActivityEvent, Metadata, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := GetEventsQuery
+
+	rows, err := e.DB.Query(ctx, query, sub, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in getting events; %v", err)
+	}
+	defer rows.Close()
+
+	var events []ActivityEvent
+	totalRecords := 0
+	for rows.Next() {
+		var event ActivityEvent
+		err = rows.Scan(&totalRecords, &event.ID, &event.Subreddit, &event.BucketStart, &event.PostCount, &event.ScoreTotal, &event.BaselineCount, &event.BaselineScore, &event.CountZ, &event.ScoreZ, &event.TopPosts, &event.TrendingTerms, &event.DetectedAt)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error in scanning events; %v", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in reading events; %v", err)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return events, metadata, nil
+}
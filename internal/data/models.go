@@ -12,20 +12,45 @@ func Handlectx() (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
+// HandleBatchctx is for the queries only the scheduled jobs and rebuilds run,
+// which go over months of posts and have no request waiting on them.
+func HandleBatchctx() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	return ctx, cancel
+}
+
 type Models struct {
-	Posts     PostModel
-	Users     UserModel
-	Polls     PollsModel
-	Surveys   SurveysModel
-	Tierlists TierlistsModel
+	Posts      PostModel
+	Users      UserModel
+	Polls      PollsModel
+	Surveys    SurveysModel
+	Tierlists  TierlistsModel
+	Events     EventsModel
+	Digests    DigestsModel
+	Stats      StatsModel
+	Stories    StoriesModel
+	Topics     TopicsModel
+	Watchlists WatchlistsModel
+	BoxOffice  BoxOfficeModel
+	Recaps     RecapsModel
+	Tracking   TrackingModel
 }
 
 func NewModel(db *pgx.Pool) Models {
 	return Models{
-		Posts:     PostModel{DB: db},
-		Users:     UserModel{DB: db},
-		Polls:     PollsModel{DB: db},
-		Surveys:   SurveysModel{DB: db},
-		Tierlists: TierlistsModel{DB: db},
+		Posts:      PostModel{DB: db},
+		Users:      UserModel{DB: db},
+		Polls:      PollsModel{DB: db},
+		Surveys:    SurveysModel{DB: db},
+		Tierlists:  TierlistsModel{DB: db},
+		Events:     EventsModel{DB: db},
+		Digests:    DigestsModel{DB: db},
+		Stats:      StatsModel{DB: db},
+		Stories:    StoriesModel{DB: db},
+		Topics:     TopicsModel{DB: db},
+		Watchlists: WatchlistsModel{DB: db},
+		BoxOffice:  BoxOfficeModel{DB: db},
+		Recaps:     RecapsModel{DB: db},
+		Tracking:   TrackingModel{DB: db},
 	}
 }
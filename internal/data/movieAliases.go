@@ -0,0 +1,44 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadMovieAliases reads the names fans call movies by on top of the titles
+// TMDB knows, as a JSON object of TMDB ids to names:
+//
+//	{"12345": ["thalapathy 67"]}
+//
+// A missing file means no extra names.
+func LoadMovieAliases(path string) (map[int][]string, error) {
+	aliases := make(map[int][]string)
+	if path == "" {
+		return aliases, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error in reading movie aliases; %v", err)
+	}
+
+	if err := json.Unmarshal(b, &aliases); err != nil {
+		return nil, fmt.Errorf("error in parsing movie aliases; %v", err)
+	}
+
+	for id, names := range aliases {
+		for _, name := range names {
+			if strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("movie %d has an empty alias", id)
+			}
+		}
+	}
+
+	return aliases, nil
+}
@@ -17,10 +17,12 @@ const (
     	subreddit_id,
     	subreddit_subscribers,
     	author,
-    	author_fullname
+    	author_fullname,
+    	flair,
+    	post_type
 	)
 	VALUES (
-    	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+    	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, COALESCE(NULLIF($17, ''), 'other')
 	)
 	ON CONFLICT(id) DO
 	UPDATE
@@ -28,6 +30,10 @@ const (
     	score = EXCLUDED.score,
     	upvote_ratio = EXCLUDED.upvote_ratio,
     	num_comments = EXCLUDED.num_comments,
+		-- keep the flair we had when it couldn't be fetched this time
+		flair = CASE WHEN EXCLUDED.flair <> '' THEN EXCLUDED.flair ELSE subreddit_posts.flair END,
+		-- and the type it was given with that flair
+		post_type = CASE WHEN EXCLUDED.flair = '' AND subreddit_posts.flair <> '' THEN subreddit_posts.post_type ELSE EXCLUDED.post_type END,
 		version = subreddit_posts.version + 1,
     	top_and_controversial = CASE
         	WHEN subreddit_posts.category <> EXCLUDED.category
@@ -37,14 +43,25 @@ const (
     	END
 	`
 
+	// DeleteOldPostsQuery keeps the last $1 days of posts, PostRetentionDays.
 	DeleteOldPostsQuery = `
 	DELETE FROM subreddit_posts
-	WHERE created_utc < NOW() - INTERVAL '365 days'
+	WHERE created_utc < NOW() - make_interval(days := $1::int)
 	`
 
+	// TopUsersQuery and ControversialUsersQuery rank the sub's users. The
+	// consistency is the share of the calendar weeks the window touches that
+	// the user posted in.
 	TopUsersQuery = `
-	select author as user,
-    	count(*) as author_count
+	select count(*) over () as total,
+		author as user,
+    	count(*) as author_count,
+		sum(score) as total_score,
+		percentile_cont(0.5) within group (order by score)::float8 as median_score,
+		sum(num_comments) as total_comments,
+		sum(score + 2 * num_comments) as impact,
+		count(distinct date_trunc('week', created_utc)) as active_weeks,
+		round(count(distinct date_trunc('week', created_utc)) / ((date_trunc('week', now())::date - date_trunc('week', now() - make_interval(days := $2))::date) / 7 + 1)::numeric, 4)::float8 as consistency
 	from subreddit_posts
 	where subreddit = $1
     	and (
@@ -56,14 +73,23 @@ const (
     	)
     	and created_utc > now() - make_interval(days := $2)
 		and author != '[deleted]'
+		and ($5::text = '' or post_type = $5)
 	group by author
-	order by author_count desc
-	limit 5
+	order by %s desc, total_score desc, author asc
+	limit $3
+	offset $4
 	`
 
 	ControversialUsersQuery = `
-	select author as user,
-    	count(*) as author_count
+	select count(*) over () as total,
+		author as user,
+    	count(*) as author_count,
+		sum(score) as total_score,
+		percentile_cont(0.5) within group (order by score)::float8 as median_score,
+		sum(num_comments) as total_comments,
+		sum(score + 2 * num_comments) as impact,
+		count(distinct date_trunc('week', created_utc)) as active_weeks,
+		round(count(distinct date_trunc('week', created_utc)) / ((date_trunc('week', now())::date - date_trunc('week', now() - make_interval(days := $2))::date) / 7 + 1)::numeric, 4)::float8 as consistency
 	from subreddit_posts
 	where subreddit = $1
     	and (
@@ -75,118 +101,102 @@ const (
     	)
     	and created_utc > now() - make_interval(days := $2)
 		and author != '[deleted]'
+		and ($5::text = '' or post_type = $5)
 	group by author
-	order by author_count desc
-	limit 5
+	order by %s desc, total_score desc, author asc
+	limit $3
+	offset $4
 	`
 
-	TopPostsQuery = `
+	// CategoryPostsQuery takes the category filter, the scoring expression
+	// and the sort direction, all from safelists in scoring.go. With $4 the
+	// posts of a story are ranked against each other and only the first stays.
+	// Posts are ranked by the exact score, it is only rounded for display.
+	CategoryPostsQuery = `
 	select id,
-    	title,
-    	selftext,
-    	author,
-    	permalink,
-    	score,
-    	upvote_ratio,
+		title,
+		selftext,
+		author,
+		permalink,
+		score,
+		upvote_ratio,
 		subreddit,
-    	num_comments,
-    	category,
-    	round((score * upvote_ratio)::numeric, 2) as top_score
-	from subreddit_posts
-	where subreddit = $1
-    	and category = 'top'
-    	and created_utc > now() - make_interval(days := $2)
-    	and top_and_controversial = false
-	order by top_score desc
-	limit 5
-	`
-
-	ControversialPostsQuery = `
-	select id,
-    	title,
-    	selftext,
-    	author,
-    	permalink,
-    	score,
-    	upvote_ratio,
-		subreddit,
-    	num_comments,
-    	category,
-    	round(
-        	(score * (1 - upvote_ratio) * num_comments)::numeric,
-        	2
-    	) as controversary_score
-	from subreddit_posts
-	where subreddit = $1
-    	and category = 'controversial'
-    	and created_utc > now() - make_interval(days := $2)
-    	and top_and_controversial = false
-	order by controversary_score desc
-	limit 5
-	`
-
-	MostHatedPostsQuery = `
-	select id,
-    	title,
-    	selftext,
-    	author,
-    	permalink,
-    	score,
-    	upvote_ratio,
-		subreddit,
-    	num_comments,
-    	category,
-		upvote_ratio as category_score
-	from subreddit_posts
-	where subreddit = $1
-    	and category = 'controversial'
-    	and created_utc > now() - make_interval(days := $2)
-    	and top_and_controversial = false
-	order by category_score asc
-	limit 5
-	`
-
-	TopAndControversialPostsQuery = `
-	select id,
-    	title,
-    	selftext,
-    	author,
-    	permalink,
-    	score,
-    	upvote_ratio,
-		subreddit,
-    	num_comments,
-    	category,
-    	round((score * upvote_ratio)::numeric, 2) as top_score
-	from subreddit_posts
-	where subreddit = $1
-    	and top_and_controversial = true
-    	and created_utc > now() - make_interval(days := $2)
-	order by top_score desc
+		num_comments,
+		category,
+		created_utc,
+		category_score,
+		story_posts - 1 as duplicates
+	from (
+		select p.id,
+			p.title,
+			p.selftext,
+			p.author,
+			p.permalink,
+			p.score,
+			p.upvote_ratio,
+			p.subreddit,
+			p.num_comments,
+			p.category,
+			p.created_utc,
+			(%[2]s)::numeric as sort_score,
+			round((%[2]s)::numeric, 2) as category_score,
+			row_number() over story as story_rank,
+			count(*) over (partition by case when $4::bool then coalesce(s.cluster_id::text, p.id) else p.id end) as story_posts
+		from subreddit_posts p
+		left join post_signatures s on s.post_id = p.id
+		where p.subreddit = $1
+			and %[1]s
+			and p.created_utc > now() - make_interval(days := $2)
+			and ($3::text = '' or p.post_type = $3)
+		window story as (
+			partition by case when $4::bool then coalesce(s.cluster_id::text, p.id) else p.id end
+			order by (%[2]s)::numeric %[3]s
+		)
+	) ranked
+	where story_rank = 1
+	order by sort_score %[3]s
 	limit 5
 	`
 
 	FrequencyOfPostsQuery = `
-	WITH date_range AS (
-    SELECT 
-        (date_trunc('week', CURRENT_DATE - INTERVAL '4 weeks')::date + make_interval(days := $2)) AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Kolkata' AS start_date,
-        (date_trunc('week', CURRENT_DATE)::date + make_interval(days := $2)) AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Kolkata' AS end_date
-	)
 	SELECT 
-    	EXTRACT(HOUR FROM (created_utc AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Kolkata')) AS hour,
-    	EXTRACT(DOW FROM (created_utc AT TIME ZONE 'UTC' AT TIME ZONE 'Asia/Kolkata')) AS day,
-    	COUNT(*) AS post_count 
-	FROM subreddit_posts, date_range
+    	EXTRACT(HOUR FROM (created_utc AT TIME ZONE 'UTC' AT TIME ZONE $2))::int AS hour,
+    	EXTRACT(DOW FROM (created_utc AT TIME ZONE 'UTC' AT TIME ZONE $2))::int AS day,
+    	COUNT(*) AS post_count,
+    	COALESCE(SUM(score), 0) AS score_total,
+    	COALESCE(SUM(num_comments), 0) AS comments_total
+	FROM subreddit_posts
 	WHERE 
-    	created_utc >= date_range.start_date
-    	AND created_utc < date_range.end_date
-    	AND subreddit = $1
+    	subreddit = $1
+    	AND created_utc >= $3
+    	AND created_utc < $4
+    	AND ($5::text = '' OR post_type = $5)
 	GROUP BY 
     	hour, day 
 	ORDER BY 
     	day ASC, hour ASC;
 	`
 
+	SlotStatsQuery = `
+	SELECT 
+    	EXTRACT(DOW FROM (created_utc AT TIME ZONE 'UTC' AT TIME ZONE $2))::int AS day,
+    	EXTRACT(HOUR FROM (created_utc AT TIME ZONE 'UTC' AT TIME ZONE $2))::int AS hour,
+    	COUNT(*) AS post_count,
+    	AVG(score)::float8 AS mean_score,
+    	COALESCE(STDDEV_SAMP(score), 0)::float8 AS stddev_score,
+    	COUNT(*) FILTER (WHERE category = 'top' OR top_and_controversial = true) AS top_count
+	FROM subreddit_posts
+	WHERE 
+    	subreddit = $1
+    	AND created_utc >= $3
+    	AND created_utc < $4
+    	AND ($5::text = '' OR post_type = $5)
+	GROUP BY 
+    	day, hour
+	ORDER BY 
+    	day ASC, hour ASC
+	`
+
 	GetAllTextsOfInterval = `
     SELECT 
       	title || ' ' || selftext AS full_text 
@@ -197,6 +207,265 @@ const (
       	AND created_utc >= now() - make_interval(days := $2)
 	`
 
+	SearchPostsQuery = `
+	SELECT COUNT(*) OVER () AS total,
+		id,
+		title,
+		author,
+		permalink,
+		score,
+		upvote_ratio,
+		subreddit,
+		num_comments,
+		category,
+		created_utc,
+		ts_rank_cd(search_vector, query) AS rank,
+		ts_headline('english', html_text.escaped_title, query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_snippet,
+		ts_headline('english', html_text.escaped_selftext, query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10') AS body_snippet
+	FROM subreddit_posts,
+		websearch_to_tsquery('english', $1) AS query,
+		-- the snippets are html, so the post's own text is escaped and only
+		-- the <mark> tags ts_headline adds are markup
+		LATERAL (
+			SELECT replace(replace(replace(replace(title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;') AS escaped_title,
+				replace(replace(replace(replace(selftext, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;') AS escaped_selftext
+		) AS html_text
+	WHERE search_vector @@ query
+		AND ($2 = '' OR subreddit = $2)
+		AND (
+			$3 = ''
+			OR ($3 = 'top_and_controversial' AND top_and_controversial = true)
+			OR (category = $3 AND top_and_controversial = false)
+		)
+		AND created_utc >= $4
+		AND created_utc < $5
+		AND ($8::text = '' OR post_type = $8)
+	ORDER BY %s
+	LIMIT $6
+	OFFSET $7
+	`
+
+	TrendingTermsQuery = `
+	SELECT pt.term,
+		SUM(pt.count) AS total
+	FROM post_terms pt
+	JOIN subreddit_posts p ON p.id = pt.post_id
+	WHERE p.subreddit = $1
+		AND p.created_utc >= $2
+		AND p.created_utc < $3
+		AND ($5::text = '' OR p.post_type = $5)
+	GROUP BY pt.term
+	ORDER BY total DESC, pt.term ASC
+	LIMIT $4
+	`
+
+	PostTermsOfIntervalQuery = `
+	SELECT pt.post_id,
+		pt.term,
+		pt.count
+	FROM post_terms pt
+	JOIN subreddit_posts p ON p.id = pt.post_id
+	WHERE p.subreddit = $1
+		AND p.created_utc >= now() - make_interval(days := $2)
+		AND ($3::text = '' OR p.post_type = $3)
+	ORDER BY pt.post_id
+	`
+
+	// SubredditTermCountsQuery counts every term of the posts of $1 and of
+	// the other subs of $2 together.
+	SubredditTermCountsQuery = `
+	SELECT pt.term,
+		COALESCE(SUM(pt.count) FILTER (WHERE p.subreddit = $1), 0) AS count,
+		COALESCE(SUM(pt.count) FILTER (WHERE p.subreddit <> $1), 0) AS other_count
+	FROM post_terms pt
+	JOIN subreddit_posts p ON p.id = pt.post_id
+	WHERE p.subreddit = ANY($2::text[])
+		AND p.created_utc >= $3
+		AND p.created_utc < $4
+		AND ($5::text = '' OR p.post_type = $5)
+	GROUP BY pt.term
+	`
+
+	DeletePostTermsQuery = `
+	DELETE FROM post_terms
+	WHERE post_id = ANY($1)
+	`
+
+	PostTextsAfterQuery = `
+	SELECT id,
+		title || ' ' || selftext AS full_text
+	FROM subreddit_posts
+	WHERE id > $1
+	ORDER BY id ASC
+	LIMIT $2
+	`
+
+	PostContentsAfterQuery = `
+	SELECT id,
+		title,
+		selftext
+	FROM subreddit_posts
+	WHERE post_type = $1
+		AND id > $2
+	ORDER BY id ASC
+	LIMIT $3
+	`
+
+	PostTitlesAfterQuery = `
+	SELECT id,
+		subreddit,
+		title,
+		flair
+	FROM subreddit_posts
+	WHERE id > $1
+	ORDER BY id ASC
+	LIMIT $2
+	`
+
+	UpdatePostTypesQuery = `
+	UPDATE subreddit_posts p
+	SET post_type = u.post_type,
+		version = p.version + 1
+	FROM unnest($1::text[], $2::text[]) AS u(id, post_type)
+	WHERE p.id = u.id
+		AND p.post_type <> u.post_type
+	`
+
+	PostTypeBreakdownQuery = `
+	SELECT post_type,
+		COUNT(*) AS posts,
+		COALESCE(SUM(score), 0) AS score_total,
+		COALESCE(SUM(num_comments), 0) AS comments_total,
+		AVG(score)::float8 AS mean_score,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY score)::float8 AS median_score,
+		AVG(num_comments)::float8 AS mean_comments,
+		AVG(upvote_ratio)::float8 AS mean_upvote_ratio,
+		COUNT(*) FILTER (WHERE category = 'top' OR top_and_controversial = true) AS top_count,
+		COUNT(*) FILTER (WHERE category = 'controversial' OR top_and_controversial = true) AS controversial_count
+	FROM subreddit_posts
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+	GROUP BY post_type
+	ORDER BY posts DESC, post_type ASC
+	`
+
+	DistributionPercentilesQuery = `
+	SELECT category,
+		CASE WHEN $4::bool THEN flair ELSE '' END AS flair_group,
+		COUNT(*) AS posts,
+		percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY score) AS score_percentiles,
+		percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY upvote_ratio) AS ratio_percentiles,
+		percentile_cont(ARRAY[0.5, 0.9, 0.99]) WITHIN GROUP (ORDER BY num_comments) AS comments_percentiles
+	FROM subreddit_posts
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+		AND ($5::text = '' OR post_type = $5)
+	GROUP BY 1, 2
+	ORDER BY 1, 2
+	`
+
+	// DistributionHistogramsQuery buckets score and comments by the edges in
+	// $5 and $7, and the upvote ratio into $6 equal buckets with 1.0 in the last.
+	DistributionHistogramsQuery = `
+	WITH base AS (
+		SELECT category,
+			CASE WHEN $4::bool THEN flair ELSE '' END AS flair_group,
+			score,
+			upvote_ratio,
+			num_comments
+		FROM subreddit_posts
+		WHERE subreddit = $1
+			AND created_utc >= $2
+			AND created_utc < $3
+			AND ($8::text = '' OR post_type = $8)
+	)
+	SELECT category, flair_group, 'score' AS metric, width_bucket(score, $5::int[]) AS bucket, COUNT(*) AS posts
+	FROM base
+	GROUP BY 1, 2, 3, 4
+	UNION ALL
+	SELECT category, flair_group, 'upvote_ratio', LEAST(width_bucket(upvote_ratio, 0, 1, $6::int), $6::int), COUNT(*)
+	FROM base
+	GROUP BY 1, 2, 3, 4
+	UNION ALL
+	SELECT category, flair_group, 'num_comments', width_bucket(num_comments, $7::int[]), COUNT(*)
+	FROM base
+	GROUP BY 1, 2, 3, 4
+	ORDER BY 1, 2, 3, 4
+	`
+
+	ExportPostsQuery = `
+	SELECT id,
+		name,
+		created_utc,
+		permalink,
+		title,
+		category,
+		selftext,
+		score,
+		upvote_ratio,
+		num_comments,
+		subreddit,
+		subreddit_id,
+		subreddit_subscribers,
+		author,
+		author_fullname,
+		flair,
+		post_type
+	FROM subreddit_posts
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+		AND ($4::text = '' OR post_type = $4)
+	ORDER BY created_utc ASC
+	`
+
+	MentionPostsQuery = `
+	SELECT id,
+		subreddit,
+		created_utc,
+		title,
+		selftext,
+		score,
+		num_comments
+	FROM subreddit_posts p
+	WHERE subreddit = ANY($1::text[])
+		AND created_utc >= $2
+		AND created_utc < $3
+		AND EXISTS (
+			SELECT 1
+			FROM unnest($4::text[]) AS alias
+			WHERE p.search_vector @@ phraseto_tsquery('english', alias)
+		)
+	ORDER BY created_utc ASC
+	`
+
+	// ReviewPostsQuery matches the names against the title alone, through
+	// the title's weight in the search vector, since a review brings up
+	// other films in passing.
+	ReviewPostsQuery = `
+	SELECT id,
+		subreddit,
+		title,
+		selftext,
+		author,
+		permalink,
+		score,
+		created_utc
+	FROM subreddit_posts p
+	WHERE subreddit = ANY($1::text[])
+		AND post_type = $5
+		AND created_utc >= $2
+		AND created_utc < $3
+		AND EXISTS (
+			SELECT 1
+			FROM unnest($4::text[]) AS alias
+			WHERE ts_filter(p.search_vector, '{a}') @@ phraseto_tsquery('english', alias)
+		)
+	ORDER BY score DESC
+	`
+
 	InsertUserQuery = `	
     INSERT INTO users (reddit_uid, username, avatar) 
     VALUES 
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PostTypeOther is the type of a post no rule matches.
+const PostTypeOther = "other"
+
+// PostTypeReview is the type of the posts ratings are read from.
+const PostTypeReview = "review"
+
+// PostTypeBoxOffice is the type of the posts collections are read from.
+const PostTypeBoxOffice = "box_office"
+
+// defaultPostTypeRules is the key of the rules every sub falls back to.
+const defaultPostTypeRules = "default"
+
+// PostTypeRule labels a post with its type when the flair is one of Flairs,
+// or the title has one of Keywords as a whole word or matches one of Patterns.
+type PostTypeRule struct {
+	Type     string   `json:"type"`
+	Flairs   []string `json:"flairs,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+
+	title *regexp.Regexp
+}
+
+// DefaultPostTypeRules are tried in order, after the rules of the post's own
+// sub. A config file can replace them under "default".
+var DefaultPostTypeRules = []PostTypeRule{
+	{
+		Type:     PostTypeBoxOffice,
+		Flairs:   []string{"Box Office", "BO", "Collections"},
+		Keywords: []string{"box office", "collection", "collections", "opening day", "worldwide gross", "nett", "footfalls"},
+		Patterns: []string{`\b\d+(\.\d+)?\s*(cr|crs|crore|crores|lakh|lakhs)\b`},
+	},
+	{
+		Type:     "trailer",
+		Flairs:   []string{"Trailer", "Teaser", "Song", "Media"},
+		Keywords: []string{"trailer", "teaser", "glimpse", "first look", "promo", "lyrical", "motion poster"},
+	},
+	{
+		Type:     PostTypeReview,
+		Flairs:   []string{"Review", "Reviews"},
+		Keywords: []string{"review", "reviews", "verdict", "watched", "rating"},
+		Patterns: []string{`\b\d+(\.\d+)?\s*/\s*(5|10)\b`},
+	},
+	{
+		Type:     "news",
+		Flairs:   []string{"News", "Update", "Announcement"},
+		Keywords: []string{"announced", "announces", "announcement", "official", "confirmed", "release date", "joins", "signed", "postponed", "to star"},
+	},
+	{
+		Type:     "meme",
+		Flairs:   []string{"Meme", "Memes", "Humour", "Humor", "Funny", "Shitpost"},
+		Keywords: []string{"meme", "memes", "shitpost", "template"},
+	},
+	{
+		Type:     "discussion",
+		Flairs:   []string{"Discussion", "Question", "Opinion", "Recommendation"},
+		Keywords: []string{"discussion", "thread", "unpopular opinion", "thoughts", "recommend", "suggest", "ama"},
+		Patterns: []string{`\?\s*$`},
+	},
+}
+
+// compile folds the keywords and patterns of the rule into one case
+// insensitive regexp over the title.
+func (r *PostTypeRule) compile() error {
+	if r.Type == "" || r.Type != strings.ToLower(r.Type) || strings.ContainsAny(r.Type, " ,") {
+		return fmt.Errorf("post type rule has invalid type %q", r.Type)
+	}
+
+	alternatives := make([]string, 0, len(r.Keywords)+len(r.Patterns))
+	for _, keyword := range r.Keywords {
+		words := strings.Fields(strings.ToLower(keyword))
+		if len(words) == 0 {
+			continue
+		}
+		for i := range words {
+			words[i] = regexp.QuoteMeta(words[i])
+		}
+		alternatives = append(alternatives, `\b`+strings.Join(words, `\s+`)+`\b`)
+	}
+
+	for _, pattern := range r.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("post type %s has invalid pattern %q; %v", r.Type, pattern, err)
+		}
+		alternatives = append(alternatives, "(?:"+pattern+")")
+	}
+
+	if len(alternatives) == 0 && len(r.Flairs) == 0 {
+		return fmt.Errorf("post type %s has no flairs, keywords or patterns", r.Type)
+	}
+
+	r.title = nil
+	if len(alternatives) > 0 {
+		r.title = regexp.MustCompile("(?i)" + strings.Join(alternatives, "|"))
+	}
+
+	return nil
+}
+
+func (r PostTypeRule) matchesFlair(flair string) bool {
+	for _, f := range r.Flairs {
+		if strings.EqualFold(f, flair) {
+			return true
+		}
+	}
+	return false
+}
+
+// PostClassifier labels posts by their title and flair.
+type PostClassifier struct {
+	rules map[string][]PostTypeRule
+	types []string
+}
+
+// LoadPostClassifier reads rules from a json object of sub to rules on top of
+// the defaults. A missing file just leaves the defaults.
+func LoadPostClassifier(path string) (*PostClassifier, error) {
+	rules := map[string][]PostTypeRule{
+		defaultPostTypeRules: append([]PostTypeRule(nil), DefaultPostTypeRules...),
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error in reading post type rules; %v", err)
+		}
+
+		if err == nil {
+			var configured map[string][]PostTypeRule
+			if err := json.Unmarshal(b, &configured); err != nil {
+				return nil, fmt.Errorf("error in parsing post type rules; %v", err)
+			}
+
+			for sub, subRules := range configured {
+				rules[strings.ToLower(sub)] = subRules
+			}
+		}
+	}
+
+	types := map[string]bool{PostTypeOther: true}
+	for sub, subRules := range rules {
+		for i := range subRules {
+			if err := subRules[i].compile(); err != nil {
+				return nil, fmt.Errorf("error in post type rules of %s; %v", sub, err)
+			}
+			types[subRules[i].Type] = true
+		}
+	}
+
+	c := &PostClassifier{rules: rules}
+	for t := range types {
+		c.types = append(c.types, t)
+	}
+	sort.Strings(c.types)
+
+	return c, nil
+}
+
+// Types lists every type a post can be labelled with.
+func (c *PostClassifier) Types() []string {
+	return c.types
+}
+
+// Classify returns the type of the first rule that matches, trying the
+// rules of the sub before the default ones. The flair is chosen by the
+// posters themselves, so a flair match anywhere wins over a title match.
+func (c *PostClassifier) Classify(sub, title, flair string) string {
+	rules := slices.Concat(c.rules[strings.ToLower(sub)], c.rules[defaultPostTypeRules])
+
+	if flair = strings.TrimSpace(flair); flair != "" {
+		for _, rule := range rules {
+			if rule.matchesFlair(flair) {
+				return rule.Type
+			}
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.title != nil && rule.title.MatchString(title) {
+			return rule.Type
+		}
+	}
+
+	return PostTypeOther
+}
@@ -0,0 +1,85 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPostClassifierClassify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "post_types.json")
+	rules := `{
+		"kollywood": [
+			{ "type": "box_office", "keywords": ["tn gross"] },
+			{ "type": "meme", "flairs": ["Troll"] }
+		],
+		"MalayalamMovies": [
+			{ "type": "review", "flairs": ["Review/Opinion"] }
+		]
+	}`
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadPostClassifier(path)
+	if err != nil {
+		t.Fatalf("LoadPostClassifier() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		sub   string
+		title string
+		flair string
+		want  string
+	}{
+		{"keyword", "bollywood", "Jawan official trailer is out", "", "trailer"},
+		{"multi word keyword", "bollywood", "Dunki box office day 4", "", PostTypeBoxOffice},
+		{"pattern", "tollywood", "Devara crosses 300 Cr worldwide", "", PostTypeBoxOffice},
+		{"earlier rule wins", "bollywood", "Animal trailer review", "", "trailer"},
+		{"flair beats title", "bollywood", "Animal trailer", "Review", PostTypeReview},
+		{"flair ignores case", "bollywood", "Tiger 3", "  meme ", "meme"},
+		{"keyword needs whole words", "bollywood", "Recollections of the nineties", "", PostTypeOther},
+		{"trailing question", "kollywood", "Which Vijay film should I start with?", "", "discussion"},
+		{"nothing matches", "kollywood", "Photo from the Leo sets", "", PostTypeOther},
+		{"unknown flair falls back to the title", "bollywood", "Leo teaser", "Fan Art", "trailer"},
+		{"sub keyword", "kollywood", "Leo TN gross so far", "", PostTypeBoxOffice},
+		{"sub keyword only in its sub", "bollywood", "Leo TN gross so far", "", PostTypeOther},
+		{"sub flair", "kollywood", "Monday mood", "Troll", "meme"},
+		{"sub flair only in its sub", "bollywood", "Monday mood", "Troll", PostTypeOther},
+		{"sub ignores case", "MalayalamMovies", "Aavesham", "Review/Opinion", PostTypeReview},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.sub, tt.title, tt.flair); got != tt.want {
+				t.Errorf("Classify(%q, %q, %q) = %q, want %q", tt.sub, tt.title, tt.flair, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPostClassifierRejectsBadRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"no type", `{"bollywood": [{"keywords": ["gossip"]}]}`},
+		{"type with spaces", `{"bollywood": [{"type": "box office", "keywords": ["gross"]}]}`},
+		{"nothing to match", `{"bollywood": [{"type": "gossip"}]}`},
+		{"invalid pattern", `{"bollywood": [{"type": "gossip", "patterns": ["(unclosed"]}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "post_types.json")
+			if err := os.WriteFile(path, []byte(tt.rules), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadPostClassifier(path); err == nil {
+				t.Error("LoadPostClassifier() error = nil, want an error")
+			}
+		})
+	}
+}
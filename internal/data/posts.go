@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,50 +18,111 @@ type PostModel struct {
 }
 
 type Post struct {
-	ID                   string    `json:"id"`
-	Name                 string    `json:"name"`
-	CreatedUTC           time.Time `json:"created_utc"`
-	Permalink            string    `json:"permalink"`
-	Title                string    `json:"title"`
-	Category             string    `json:"category"`
-	Selftext             string    `json:"selftext"`
-	Score                int       `json:"score"`
-	UpvoteRatio          float64   `json:"upvote_ratio"`
-	NumComments          int       `json:"num_comments"`
-	Subreddit            string    `json:"subreddit"`
-	SubredditID          string    `json:"subreddit_id"`
-	SubredditSubscribers int       `json:"subreddit_subscribers"`
-	Author               string    `json:"author"`
-	AuthorFullname       string    `json:"author_fullname"`
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	CreatedUTC           time.Time      `json:"created_utc"`
+	Permalink            string         `json:"permalink"`
+	Title                string         `json:"title"`
+	Category             string         `json:"category"`
+	Selftext             string         `json:"selftext"`
+	Score                int            `json:"score"`
+	UpvoteRatio          float64        `json:"upvote_ratio"`
+	NumComments          int            `json:"num_comments"`
+	Subreddit            string         `json:"subreddit"`
+	SubredditID          string         `json:"subreddit_id"`
+	SubredditSubscribers int            `json:"subreddit_subscribers"`
+	Author               string         `json:"author"`
+	AuthorFullname       string         `json:"author_fullname"`
+	Flair                string         `json:"flair"`
+	PostType             string         `json:"post_type"`
+	Terms                map[string]int `json:"-"`
+}
+
+type PostText struct {
+	ID   string
+	Text string
 }
 
 type PostsWrapper struct {
 	Posts []Post `json:"posts"`
 }
 type TopUsers struct {
-	User      string `json:"user"`
-	PostCount int    `json:"post_count"`
-	Avatar    string `json:"avatar,omitempty"`
+	User          string  `json:"user"`
+	PostCount     int     `json:"post_count"`
+	TotalScore    int     `json:"total_score"`
+	MedianScore   float64 `json:"median_score"`
+	TotalComments int     `json:"total_comments"`
+	Impact        int     `json:"impact"`
+	ActiveWeeks   int     `json:"active_weeks"`
+	Consistency   float64 `json:"consistency"`
+	Avatar        string  `json:"avatar,omitempty"`
 }
 
 type TopPosts struct {
-	ID            string  `json:"id"`
-	Title         string  `json:"title"`
-	Body          string  `json:"body"`
-	Author        string  `json:"author"`
-	URL           string  `json:"url"`
-	Upvotes       int     `json:"upvotes"`
-	UpvoteRatio   float64 `json:"upvote_ratio"`
-	Subreddit     string  `json:"subreddit"`
-	NumComments   int     `json:"num_comments"`
-	Category      string  `json:"category"`
-	CategoryScore float64 `json:"category_score"`
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	Body          string    `json:"body"`
+	Author        string    `json:"author"`
+	URL           string    `json:"url"`
+	Upvotes       int       `json:"upvotes"`
+	UpvoteRatio   float64   `json:"upvote_ratio"`
+	Subreddit     string    `json:"subreddit"`
+	NumComments   int       `json:"num_comments"`
+	Category      string    `json:"category"`
+	CreatedUTC    time.Time `json:"created_utc"`
+	CategoryScore float64   `json:"category_score"`
+	Duplicates    int       `json:"duplicates"`
 }
 
 type PostFrequency struct {
-	Hour  int
-	Day   int
-	Count int
+	Hour          int `json:"hour"`
+	Day           int `json:"day"`
+	Count         int `json:"count"`
+	ScoreTotal    int `json:"score_total"`
+	CommentsTotal int `json:"comments_total"`
+}
+
+type SlotStats struct {
+	Day         int
+	Hour        int
+	PostCount   int
+	MeanScore   float64
+	StdDevScore float64
+	TopCount    int
+}
+
+type FrequencyParams struct {
+	Timezone string
+	From     time.Time
+	To       time.Time
+	PostType string
+}
+
+type SearchParams struct {
+	Query    string
+	Sub      string
+	Category string
+	From     time.Time
+	To       time.Time
+	PostType string
+}
+
+// SearchResult is a post matching a search. The snippets are escaped html
+// with the matches wrapped in <mark>.
+type SearchResult struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Author       string    `json:"author"`
+	URL          string    `json:"url"`
+	Upvotes      int       `json:"upvotes"`
+	UpvoteRatio  float64   `json:"upvote_ratio"`
+	Subreddit    string    `json:"subreddit"`
+	NumComments  int       `json:"num_comments"`
+	Category     string    `json:"category"`
+	CreatedUTC   time.Time `json:"created_utc"`
+	Rank         float64   `json:"rank"`
+	TitleSnippet string    `json:"title_snippet"`
+	BodySnippet  string    `json:"body_snippet"`
 }
 
 func (p PostModel) GetTrendingWords(sub string, interval int) ([]string, error) {
@@ -88,16 +150,260 @@ func (p PostModel) GetTrendingWords(sub string, interval int) ([]string, error)
 	return words, nil
 }
 
-func (p PostModel) GetPostFrequency(sub string) ([]PostFrequency, error) {
+func (p PostModel) GetTrendingTerms(sub, postType string, from, to time.Time, limit int) ([]TermCount, error) {
 	ctx, cancel := Handlectx()
 	defer cancel()
 
-	query := FrequencyOfPostsQuery
+	query := TrendingTermsQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, from.UTC(), to.UTC(), limit, postType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting trending terms; %v", err)
+	}
+	defer rows.Close()
+
+	terms := []TermCount{}
+	for rows.Next() {
+		var term TermCount
+		err = rows.Scan(&term.Term, &term.Count)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning trending terms; %v", err)
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, nil
+}
+
+// GetPostTerms returns the terms of every post of the interval keyed by post id.
+func (p PostModel) GetPostTerms(sub, postType string, interval int) (map[string]map[string]int, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := PostTermsOfIntervalQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, interval, postType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting post terms; %v", err)
+	}
+	defer rows.Close()
+
+	postTerms := make(map[string]map[string]int)
+	for rows.Next() {
+		var postID, term string
+		var count int
+		err = rows.Scan(&postID, &term, &count)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning post terms; %v", err)
+		}
+
+		if postTerms[postID] == nil {
+			postTerms[postID] = make(map[string]int)
+		}
+		postTerms[postID][term] = count
+	}
+
+	return postTerms, nil
+}
+
+// GetSubredditTermCounts counts the terms of the posts of sub and of the
+// others together, for the posts created in [from, to).
+func (p PostModel) GetSubredditTermCounts(sub string, others []string, postType string, from, to time.Time) (map[string]int, map[string]int, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := SubredditTermCountsQuery
+
+	subs := append([]string{sub}, others...)
+
+	rows, err := p.DB.Query(ctx, query, sub, subs, from.UTC(), to.UTC(), postType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in getting subreddit term counts; %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	otherCounts := make(map[string]int)
+	for rows.Next() {
+		var term string
+		var count, otherCount int
+		err = rows.Scan(&term, &count, &otherCount)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in scanning subreddit term counts; %v", err)
+		}
+
+		if count > 0 {
+			counts[term] = count
+		}
+		if otherCount > 0 {
+			otherCounts[term] = otherCount
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error in reading subreddit term counts; %v", err)
+	}
+
+	return counts, otherCounts, nil
+}
+
+// GetPostTextsAfter pages through every stored post in id order, for
+// rebuilding data derived from the post texts.
+func (p PostModel) GetPostTextsAfter(afterID string, limit int) ([]PostText, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := PostTextsAfterQuery
+
+	rows, err := p.DB.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting post texts; %v", err)
+	}
+	defer rows.Close()
+
+	var texts []PostText
+	for rows.Next() {
+		var text PostText
+		err = rows.Scan(&text.ID, &text.Text)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning post texts; %v", err)
+		}
+		texts = append(texts, text)
+	}
+
+	return texts, nil
+}
+
+type PostContent struct {
+	ID       string
+	Title    string
+	Selftext string
+}
+
+// GetPostContentsAfter pages through the stored posts of a type in id order.
+func (p PostModel) GetPostContentsAfter(postType, afterID string, limit int) ([]PostContent, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := PostContentsAfterQuery
 
-	weekday := time.Now().Weekday()
-	weekdayInt := int(weekday) - 1
+	rows, err := p.DB.Query(ctx, query, postType, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting post contents; %v", err)
+	}
+	defer rows.Close()
 
-	rows, err := p.DB.Query(ctx, query, sub, weekdayInt)
+	var contents []PostContent
+	for rows.Next() {
+		var content PostContent
+		err = rows.Scan(&content.ID, &content.Title, &content.Selftext)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning post contents; %v", err)
+		}
+		contents = append(contents, content)
+	}
+
+	return contents, nil
+}
+
+// ReplacePostTerms swaps the stored terms of the given posts for theirs.
+func (p PostModel) ReplacePostTerms(posts []Post) (err error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	tx, err := p.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", r)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	return insertPostTerms(ctx, tx, posts)
+}
+
+// insertPostTerms replaces the terms of every post that has them. A post can
+// come in more than once from the different listings, the last one wins.
+func insertPostTerms(ctx context.Context, tx pg.Tx, posts []Post) error {
+	termsByPost := make(map[string]map[string]int)
+	for _, post := range posts {
+		if post.Terms != nil {
+			termsByPost[post.ID] = post.Terms
+		}
+	}
+
+	if len(termsByPost) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(termsByPost))
+	var rows [][]any
+	for id, terms := range termsByPost {
+		ids = append(ids, id)
+		for term, count := range terms {
+			rows = append(rows, []any{id, term, count})
+		}
+	}
+
+	if _, err := tx.Exec(ctx, DeletePostTermsQuery, ids); err != nil {
+		return fmt.Errorf("error in deleting post terms: %v", err)
+	}
+
+	_, err := tx.CopyFrom(ctx, pg.Identifier{"post_terms"}, []string{"post_id", "term", "count"}, pg.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("error in inserting post terms: %v", err)
+	}
+
+	return nil
+}
+
+// StreamPosts hands every post of the range to fn as it comes off the wire,
+// so an export never holds the whole range in memory. It runs on the caller's
+// context instead of Handlectx, a big export takes longer than its timeout.
+func (p PostModel) StreamPosts(ctx context.Context, sub, postType string, from, to time.Time, fn func(Post) error) error {
+	query := ExportPostsQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, from.UTC(), to.UTC(), postType)
+	if err != nil {
+		return fmt.Errorf("error in exporting posts; %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var post Post
+		err = rows.Scan(&post.ID, &post.Name, &post.CreatedUTC, &post.Permalink, &post.Title, &post.Category, &post.Selftext, &post.Score, &post.UpvoteRatio, &post.NumComments, &post.Subreddit, &post.SubredditID, &post.SubredditSubscribers, &post.Author, &post.AuthorFullname, &post.Flair, &post.PostType)
+		if err != nil {
+			return fmt.Errorf("error in scanning exported posts; %v", err)
+		}
+
+		if err = fn(post); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error in exporting posts; %v", err)
+	}
+
+	return nil
+}
+
+func (p PostModel) GetPostFrequency(sub string, params FrequencyParams) ([]PostFrequency, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := FrequencyOfPostsQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, params.Timezone, params.From.UTC(), params.To.UTC(), params.PostType)
 	if err != nil {
 		return nil, fmt.Errorf("error in getting post frequency by day of week; %v", err)
 	}
@@ -106,7 +412,7 @@ func (p PostModel) GetPostFrequency(sub string) ([]PostFrequency, error) {
 	var postFrequency []PostFrequency
 	for rows.Next() {
 		var frequency PostFrequency
-		err = rows.Scan(&frequency.Hour, &frequency.Day, &frequency.Count)
+		err = rows.Scan(&frequency.Hour, &frequency.Day, &frequency.Count, &frequency.ScoreTotal, &frequency.CommentsTotal)
 		if err != nil {
 			return nil, fmt.Errorf("error in scanning post frequency; %v", err)
 		}
@@ -115,26 +421,49 @@ func (p PostModel) GetPostFrequency(sub string) ([]PostFrequency, error) {
 	return postFrequency, nil
 }
 
-func (p PostModel) GetTopPosts(sub string, category string, interval int) ([]TopPosts, error) {
+func (p PostModel) GetSlotStats(sub string, params FrequencyParams) ([]SlotStats, error) {
 	ctx, cancel := Handlectx()
 	defer cancel()
 
-	var query string
+	query := SlotStatsQuery
 
-	switch category {
-	case "top":
-		query = TopPostsQuery
-	case "controversial":
-		query = ControversialPostsQuery
-	case "top_and_controversial":
-		query = TopAndControversialPostsQuery
-	case "hated":
-		query = MostHatedPostsQuery
-	default:
+	rows, err := p.DB.Query(ctx, query, sub, params.Timezone, params.From.UTC(), params.To.UTC(), params.PostType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting slot stats; %v", err)
+	}
+	defer rows.Close()
+
+	var slots []SlotStats
+	for rows.Next() {
+		var slot SlotStats
+		err = rows.Scan(&slot.Day, &slot.Hour, &slot.PostCount, &slot.MeanScore, &slot.StdDevScore, &slot.TopCount)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning slot stats; %v", err)
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
+
+// GetTopPosts ranks the posts of a category by the given scoring profile.
+// With collapse only the best ranked post of every story is kept.
+func (p PostModel) GetTopPosts(sub, category, postType string, interval int, scoring ScoringProfile, collapse bool) ([]TopPosts, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	filter, ok := categoryFilters[category]
+	if !ok {
 		return nil, fmt.Errorf("invalid category: %s", category)
 	}
 
-	rows, err := p.DB.Query(ctx, query, sub, interval)
+	if err := scoring.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(CategoryPostsQuery, filter, scoring.expression(), scoring.direction())
+
+	rows, err := p.DB.Query(ctx, query, sub, interval, postType, collapse)
 	if err != nil {
 		if err == pg.ErrNoRows {
 			return []TopPosts{}, nil
@@ -146,7 +475,7 @@ func (p PostModel) GetTopPosts(sub string, category string, interval int) ([]Top
 	var topPosts []TopPosts
 	for rows.Next() {
 		var topPost TopPosts
-		err = rows.Scan(&topPost.ID, &topPost.Title, &topPost.Body, &topPost.Author, &topPost.URL, &topPost.Upvotes, &topPost.UpvoteRatio, &topPost.Subreddit, &topPost.NumComments, &topPost.Category, &topPost.CategoryScore)
+		err = rows.Scan(&topPost.ID, &topPost.Title, &topPost.Body, &topPost.Author, &topPost.URL, &topPost.Upvotes, &topPost.UpvoteRatio, &topPost.Subreddit, &topPost.NumComments, &topPost.Category, &topPost.CreatedUTC, &topPost.CategoryScore, &topPost.Duplicates)
 		if err != nil {
 			return nil, fmt.Errorf("error in scanning top posts; %v", err)
 		}
@@ -156,7 +485,22 @@ func (p PostModel) GetTopPosts(sub string, category string, interval int) ([]Top
 	return topPosts, nil
 }
 
-func (p PostModel) GetTopUser(sub string, category string, interval int) ([]TopUsers, error) {
+func topUsersOrderBy(filters Filters) string {
+	switch filters.SortColumn() {
+	case "score":
+		return "total_score"
+	case "median":
+		return "median_score"
+	case "impact":
+		return "impact"
+	case "consistency":
+		return "consistency"
+	default:
+		return "author_count"
+	}
+}
+
+func (p PostModel) GetTopUser(sub, category, postType string, interval int, filters Filters) ([]TopUsers, Metadata, error) {
 	ctx, cancel := Handlectx()
 	defer cancel()
 	var query string
@@ -167,26 +511,74 @@ func (p PostModel) GetTopUser(sub string, category string, interval int) ([]TopU
 	case "controversial":
 		query = ControversialUsersQuery
 	default:
-		return nil, fmt.Errorf("invalid category: %s", category)
+		return nil, Metadata{}, fmt.Errorf("invalid category: %s", category)
 	}
 
-	rows, err := p.DB.Query(ctx, query, sub, interval)
+	query = fmt.Sprintf(query, topUsersOrderBy(filters))
+
+	rows, err := p.DB.Query(ctx, query, sub, interval, filters.limit(), filters.offset(), postType)
 	if err != nil {
-		return nil, fmt.Errorf("error in getting top users; %v", err)
+		return nil, Metadata{}, fmt.Errorf("error in getting top users; %v", err)
 	}
 	defer rows.Close()
 
 	var topUsers []TopUsers
+	totalRecords := 0
 	for rows.Next() {
 		var topUser TopUsers
-		err = rows.Scan(&topUser.User, &topUser.PostCount)
+		err = rows.Scan(&totalRecords, &topUser.User, &topUser.PostCount, &topUser.TotalScore, &topUser.MedianScore, &topUser.TotalComments, &topUser.Impact, &topUser.ActiveWeeks, &topUser.Consistency)
 		if err != nil {
-			return nil, fmt.Errorf("error in scanning top users; %v", err)
+			return nil, Metadata{}, fmt.Errorf("error in scanning top users; %v", err)
 		}
 		topUsers = append(topUsers, topUser)
 	}
 
-	return topUsers, nil
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return topUsers, metadata, nil
+}
+
+func searchOrderBy(filters Filters) string {
+	switch filters.SortColumn() {
+	case "score":
+		return "score DESC, rank DESC"
+	case "recency":
+		return "created_utc DESC"
+	default:
+		return "rank DESC, created_utc DESC"
+	}
+}
+
+func (p PostModel) SearchPosts(params SearchParams, filters Filters) ([]SearchResult, Metadata, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := fmt.Sprintf(SearchPostsQuery, searchOrderBy(filters))
+
+	rows, err := p.DB.Query(ctx, query, params.Query, params.Sub, params.Category, params.From.UTC(), params.To.UTC(), filters.limit(), filters.offset(), params.PostType)
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in searching posts; %v", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	totalRecords := 0
+	for rows.Next() {
+		var result SearchResult
+		err = rows.Scan(&totalRecords, &result.ID, &result.Title, &result.Author, &result.URL, &result.Upvotes, &result.UpvoteRatio, &result.Subreddit, &result.NumComments, &result.Category, &result.CreatedUTC, &result.Rank, &result.TitleSnippet, &result.BodySnippet)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error in scanning search results; %v", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in iterating search results; %v", err)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return results, metadata, nil
 }
 
 func (p PostModel) DumpJson(filename string) error {
@@ -222,7 +614,7 @@ func (p PostModel) InsertOnePost(post Post) error {
 
 	query := InsertPostsQuery
 
-	_, err := p.DB.Exec(ctx, query, post.ID, post.Name, post.CreatedUTC, post.Permalink, post.Title, post.Category, post.Selftext, post.Score, post.UpvoteRatio, post.NumComments, post.Subreddit, post.SubredditID, post.SubredditSubscribers, post.Author, post.AuthorFullname)
+	_, err := p.DB.Exec(ctx, query, post.ID, post.Name, post.CreatedUTC, post.Permalink, post.Title, post.Category, post.Selftext, post.Score, post.UpvoteRatio, post.NumComments, post.Subreddit, post.SubredditID, post.SubredditSubscribers, post.Author, post.AuthorFullname, post.Flair, post.PostType)
 	if err != nil {
 		return fmt.Errorf("error in inserting post: %v", err)
 	}
@@ -254,16 +646,20 @@ func (p PostModel) InsertDailyPosts(dailyPosts []Post) (err error) {
 	query := InsertPostsQuery
 
 	for _, post := range dailyPosts {
-		_, err = tx.Exec(ctx, query, post.ID, post.Name, post.CreatedUTC, post.Permalink, post.Title, post.Category, post.Selftext, post.Score, post.UpvoteRatio, post.NumComments, post.Subreddit, post.SubredditID, post.SubredditSubscribers, post.Author, post.AuthorFullname)
+		_, err = tx.Exec(ctx, query, post.ID, post.Name, post.CreatedUTC, post.Permalink, post.Title, post.Category, post.Selftext, post.Score, post.UpvoteRatio, post.NumComments, post.Subreddit, post.SubredditID, post.SubredditSubscribers, post.Author, post.AuthorFullname, post.Flair, post.PostType)
 		if err != nil {
 			err = fmt.Errorf("error in inserting post: %v", err)
 			return
 		}
 	}
 
+	if err = insertPostTerms(ctx, tx, dailyPosts); err != nil {
+		return
+	}
+
 	query = DeleteOldPostsQuery
 
-	deleted, err := tx.Exec(ctx, query)
+	deleted, err := tx.Exec(ctx, query, PostRetentionDays)
 	if err != nil {
 		err = fmt.Errorf("error in deleting old posts: %v", err)
 		return
@@ -271,5 +667,255 @@ func (p PostModel) InsertDailyPosts(dailyPosts []Post) (err error) {
 
 	fmt.Println("Deleted old posts: ", deleted.RowsAffected())
 
+	query = DeleteEmptyStoryClustersQuery
+
+	if _, err = tx.Exec(ctx, query); err != nil {
+		err = fmt.Errorf("error in deleting empty stories: %v", err)
+		return
+	}
+
 	return nil
 }
+
+type DistributionParams struct {
+	From         time.Time
+	To           time.Time
+	ByFlair      bool
+	ScoreEdges   []int
+	RatioBuckets int
+	CommentEdges []int
+	PostType     string
+}
+
+type DistributionPercentiles struct {
+	Category string
+	Flair    string
+	Posts    int
+	Score    []float64
+	Ratio    []float64
+	Comments []float64
+}
+
+type HistogramCount struct {
+	Category string
+	Flair    string
+	Metric   string
+	Bucket   int
+	Count    int
+}
+
+// GetDistributionPercentiles returns the p50, p90 and p99 of score, upvote
+// ratio and comments for every category, and every flair when asked to.
+func (p PostModel) GetDistributionPercentiles(sub string, params DistributionParams) ([]DistributionPercentiles, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DistributionPercentilesQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, params.From.UTC(), params.To.UTC(), params.ByFlair, params.PostType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting distribution percentiles; %v", err)
+	}
+	defer rows.Close()
+
+	var groups []DistributionPercentiles
+	for rows.Next() {
+		var group DistributionPercentiles
+		err = rows.Scan(&group.Category, &group.Flair, &group.Posts, &group.Score, &group.Ratio, &group.Comments)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning distribution percentiles; %v", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (p PostModel) GetDistributionHistograms(sub string, params DistributionParams) ([]HistogramCount, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DistributionHistogramsQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, params.From.UTC(), params.To.UTC(), params.ByFlair, params.ScoreEdges, params.RatioBuckets, params.CommentEdges, params.PostType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting distribution histograms; %v", err)
+	}
+	defer rows.Close()
+
+	var counts []HistogramCount
+	for rows.Next() {
+		var count HistogramCount
+		err = rows.Scan(&count.Category, &count.Flair, &count.Metric, &count.Bucket, &count.Count)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning distribution histograms; %v", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+type PostTitle struct {
+	ID        string
+	Subreddit string
+	Title     string
+	Flair     string
+}
+
+type PostTypeStats struct {
+	PostType           string  `json:"type"`
+	Posts              int     `json:"posts"`
+	Share              float64 `json:"share"`
+	ScoreTotal         int     `json:"score_total"`
+	CommentsTotal      int     `json:"comments_total"`
+	MeanScore          float64 `json:"mean_score"`
+	MedianScore        float64 `json:"median_score"`
+	MeanComments       float64 `json:"mean_comments"`
+	MeanUpvoteRatio    float64 `json:"mean_upvote_ratio"`
+	TopCount           int     `json:"top_count"`
+	ControversialCount int     `json:"controversial_count"`
+}
+
+// GetPostTitlesAfter pages through every stored post in id order, for
+// classifying them again.
+func (p PostModel) GetPostTitlesAfter(afterID string, limit int) ([]PostTitle, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := PostTitlesAfterQuery
+
+	rows, err := p.DB.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting post titles; %v", err)
+	}
+	defer rows.Close()
+
+	var titles []PostTitle
+	for rows.Next() {
+		var title PostTitle
+		err = rows.Scan(&title.ID, &title.Subreddit, &title.Title, &title.Flair)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning post titles; %v", err)
+		}
+		titles = append(titles, title)
+	}
+
+	return titles, nil
+}
+
+// UpdatePostTypes sets the type of every post in ids to the type at the same
+// index, returning how many posts changed.
+func (p PostModel) UpdatePostTypes(ids, postTypes []string) (int64, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UpdatePostTypesQuery
+
+	tag, err := p.DB.Exec(ctx, query, ids, postTypes)
+	if err != nil {
+		return 0, fmt.Errorf("error in updating post types; %v", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (p PostModel) GetPostTypeBreakdown(sub string, from, to time.Time) ([]PostTypeStats, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := PostTypeBreakdownQuery
+
+	rows, err := p.DB.Query(ctx, query, sub, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error in getting post type breakdown; %v", err)
+	}
+	defer rows.Close()
+
+	stats := []PostTypeStats{}
+	for rows.Next() {
+		var s PostTypeStats
+		err = rows.Scan(&s.PostType, &s.Posts, &s.ScoreTotal, &s.CommentsTotal, &s.MeanScore, &s.MedianScore, &s.MeanComments, &s.MeanUpvoteRatio, &s.TopCount, &s.ControversialCount)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning post type breakdown; %v", err)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+type MentionPost struct {
+	ID          string
+	Subreddit   string
+	CreatedUTC  time.Time
+	Title       string
+	Selftext    string
+	Score       int
+	NumComments int
+}
+
+// GetMentionPosts returns the posts of the subs created in [from, to) that
+// mention any of the names as a phrase.
+func (p PostModel) GetMentionPosts(subs, names []string, from, to time.Time) ([]MentionPost, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := MentionPostsQuery
+
+	rows, err := p.DB.Query(ctx, query, subs, from.UTC(), to.UTC(), names)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting mention posts; %v", err)
+	}
+	defer rows.Close()
+
+	var posts []MentionPost
+	for rows.Next() {
+		var post MentionPost
+		err = rows.Scan(&post.ID, &post.Subreddit, &post.CreatedUTC, &post.Title, &post.Selftext, &post.Score, &post.NumComments)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning mention posts; %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+type ReviewPost struct {
+	ID         string    `json:"id"`
+	Subreddit  string    `json:"subreddit"`
+	Title      string    `json:"title"`
+	Selftext   string    `json:"-"`
+	Author     string    `json:"author"`
+	URL        string    `json:"url"`
+	Upvotes    int       `json:"upvotes"`
+	CreatedUTC time.Time `json:"created_utc"`
+}
+
+// GetReviewPosts returns the review posts of the subs created in [from, to)
+// with any of the names in the title, highest score first.
+func (p PostModel) GetReviewPosts(subs, names []string, from, to time.Time) ([]ReviewPost, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := ReviewPostsQuery
+
+	rows, err := p.DB.Query(ctx, query, subs, from.UTC(), to.UTC(), names, PostTypeReview)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting review posts; %v", err)
+	}
+	defer rows.Close()
+
+	var posts []ReviewPost
+	for rows.Next() {
+		var post ReviewPost
+		err = rows.Scan(&post.ID, &post.Subreddit, &post.Title, &post.Selftext, &post.Author, &post.URL, &post.Upvotes, &post.CreatedUTC)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning review posts; %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
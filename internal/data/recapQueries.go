@@ -0,0 +1,244 @@
+package data
+
+const (
+	UpsertRecapQuery = `
+	INSERT INTO recaps (
+		subreddit,
+		year,
+		complete,
+		version,
+		document
+	)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (subreddit, year) DO
+	UPDATE
+	SET
+		complete = EXCLUDED.complete,
+		version = EXCLUDED.version,
+		document = EXCLUDED.document,
+		created_at = NOW()
+	`
+
+	RecapQuery = `
+	SELECT id,
+		subreddit,
+		year,
+		complete,
+		version,
+		document,
+		created_at
+	FROM recaps
+	WHERE subreddit = $1
+		AND year = $2
+	`
+
+	// RecapPostsQuery ranks the posts of the category by a scoring profile,
+	// within each month when $5 is set and over the whole period otherwise.
+	// The score is only rounded for display, ranking uses the exact one.
+	RecapPostsQuery = `
+	select month,
+		id,
+		title,
+		author,
+		permalink,
+		score,
+		upvote_ratio,
+		num_comments,
+		created_utc,
+		category_score
+	from (
+		select to_char(created_utc, 'YYYY-MM') as month,
+			id,
+			title,
+			author,
+			permalink,
+			score,
+			upvote_ratio,
+			num_comments,
+			created_utc,
+			round((%[2]s)::numeric, 2) as category_score,
+			row_number() over (
+				partition by case when $5::bool then to_char(created_utc, 'YYYY-MM') else '' end
+				order by (%[2]s)::numeric %[3]s, id asc
+			) as period_rank
+		from subreddit_posts
+		where subreddit = $1
+			and %[1]s
+			and created_utc >= $2
+			and created_utc < $3
+	) ranked
+	where period_rank <= $4
+	order by case when $5::bool then month else '' end asc, period_rank asc
+	`
+
+	RecapTotalsQuery = `
+	SELECT COUNT(*) AS posts,
+		COALESCE(SUM(num_comments), 0) AS comments,
+		COUNT(DISTINCT author) FILTER (WHERE author != '[deleted]') AS authors
+	FROM subreddit_posts
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+	`
+
+	RecapContributorsQuery = `
+	SELECT author,
+		COUNT(*) AS post_count,
+		SUM(score) AS total_score,
+		SUM(num_comments) AS total_comments,
+		SUM(score + 2 * num_comments) AS impact,
+		COUNT(DISTINCT date_trunc('month', created_utc)) AS active_months
+	FROM subreddit_posts
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+		AND author != '[deleted]'
+	GROUP BY author
+	ORDER BY post_count DESC, impact DESC, author ASC
+	LIMIT $4
+	`
+
+	// RecapMentionsQuery totals the posts that mention any of the names in
+	// $4 as a phrase, matching them like MentionPostsQuery.
+	RecapMentionsQuery = `
+	SELECT COUNT(*) AS posts,
+		COALESCE(SUM(score), 0) AS score_total,
+		COALESCE(SUM(num_comments), 0) AS comments_total
+	FROM subreddit_posts p
+	WHERE subreddit = $1
+		AND created_utc >= $2
+		AND created_utc < $3
+		AND EXISTS (
+			SELECT 1
+			FROM unnest($4::text[]) AS alias
+			WHERE p.search_vector @@ phraseto_tsquery('english', alias)
+		)
+	`
+
+	DeleteTermMonthsQuery = `
+	DELETE FROM subreddit_term_months
+	WHERE subreddit = $1
+		AND month >= $2::timestamp
+		AND month < $3::timestamp
+	`
+
+	// InsertTermMonthsQuery counts the terms of the sub's posts by the month
+	// they were created in.
+	InsertTermMonthsQuery = `
+	INSERT INTO subreddit_term_months (subreddit, month, term, count)
+	SELECT p.subreddit,
+		date_trunc('month', p.created_utc)::date AS month,
+		pt.term,
+		SUM(pt.count) AS count
+	FROM post_terms pt
+	JOIN subreddit_posts p ON p.id = pt.post_id
+	WHERE p.subreddit = $1
+		AND p.created_utc >= $2::timestamp
+		AND p.created_utc < $3::timestamp
+	GROUP BY p.subreddit, month, pt.term
+	`
+
+	// RecapTermCountsQuery counts the terms of the sub's posts in [$2, $3)
+	// and [$3, $4). Months with stored counts are read from them, the rest
+	// from the posts still around.
+	RecapTermCountsQuery = `
+	WITH stored AS (
+		SELECT month,
+			term,
+			count
+		FROM subreddit_term_months
+		WHERE subreddit = $1
+			AND month >= $2::timestamp
+			AND month < $4::timestamp
+	),
+	live AS (
+		SELECT date_trunc('month', p.created_utc)::date AS month,
+			pt.term,
+			SUM(pt.count)::int AS count
+		FROM post_terms pt
+		JOIN subreddit_posts p ON p.id = pt.post_id
+		WHERE p.subreddit = $1
+			AND p.created_utc >= $2::timestamp
+			AND p.created_utc < $4::timestamp
+			AND NOT EXISTS (
+				SELECT 1
+				FROM stored s
+				WHERE s.month = date_trunc('month', p.created_utc)::date
+			)
+		GROUP BY month, pt.term
+	)
+	SELECT term,
+		COALESCE(SUM(count) FILTER (WHERE month < $3::timestamp), 0) AS before_count,
+		COALESCE(SUM(count) FILTER (WHERE month >= $3::timestamp), 0) AS after_count
+	FROM (
+		SELECT month, term, count FROM stored
+		UNION ALL
+		SELECT month, term, count FROM live
+	) counts
+	GROUP BY term
+	`
+
+	UserRecapPollsQuery = `
+	SELECT COUNT(*) OVER () AS total,
+		p.id,
+		p.subreddit,
+		p.title,
+		COUNT(v.id) AS votes
+	FROM polls p
+	LEFT JOIN poll_votes v ON v.poll_id = p.id
+	WHERE p.reddit_uid = $1
+		AND p.start_time >= $2
+		AND p.start_time < $3
+	GROUP BY p.id, p.subreddit, p.title
+	ORDER BY votes DESC, p.id DESC
+	LIMIT $4
+	`
+
+	// UserRecapVotesQuery counts the votes of the user by sub, and how many
+	// went to the option that is leading the poll.
+	UserRecapVotesQuery = `
+	SELECT p.subreddit,
+		COUNT(*) AS votes,
+		COUNT(*) FILTER (WHERE v.option_id = leading.option_id) AS with_leader
+	FROM poll_votes v
+	JOIN polls p ON p.id = v.poll_id
+	LEFT JOIN LATERAL (
+		SELECT pv.option_id
+		FROM poll_votes pv
+		WHERE pv.poll_id = v.poll_id
+		GROUP BY pv.option_id
+		ORDER BY COUNT(*) DESC, pv.option_id ASC
+		LIMIT 1
+	) leading ON true
+	WHERE v.reddit_uid = $1
+		AND v.created_at >= $2
+		AND v.created_at < $3
+	GROUP BY p.subreddit
+	ORDER BY votes DESC, p.subreddit ASC
+	`
+
+	UserRecapSurveysQuery = `
+	SELECT (
+			SELECT COUNT(*)
+			FROM surveys
+			WHERE reddit_uid = $1
+				AND created_at >= $2
+				AND created_at < $3
+		) AS created,
+		(
+			SELECT COUNT(*)
+			FROM survey_responses r
+			JOIN surveys s ON s.id = r.survey_id
+			WHERE s.reddit_uid = $1
+				AND r.created_at >= $2
+				AND r.created_at < $3
+		) AS responses_received,
+		(
+			SELECT COUNT(*)
+			FROM survey_responses
+			WHERE reddit_uid = $1
+				AND created_at >= $2
+				AND created_at < $3
+		) AS answered
+	`
+)
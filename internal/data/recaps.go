@@ -0,0 +1,377 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pg "github.com/jackc/pgx/v5"
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrRecapNotFound = errors.New("recap not found")
+
+// PostRetentionDays is how long posts are kept, after that only what was
+// stored from them remains.
+const PostRetentionDays = 365
+
+type RecapsModel struct {
+	DB *pgx.Pool
+}
+
+// Recap is the precomputed recap of a sub's year. It is complete once the
+// year is over, until then it covers the year so far.
+type Recap struct {
+	ID        int             `json:"id"`
+	Subreddit string          `json:"subreddit"`
+	Year      int             `json:"year"`
+	Complete  bool            `json:"complete"`
+	Version   int             `json:"version"`
+	Document  json.RawMessage `json:"document"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type RecapPost struct {
+	Month         string    `json:"month"`
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	URL           string    `json:"url"`
+	Upvotes       int       `json:"upvotes"`
+	UpvoteRatio   float64   `json:"upvote_ratio"`
+	NumComments   int       `json:"num_comments"`
+	CreatedUTC    time.Time `json:"created_utc"`
+	CategoryScore float64   `json:"category_score"`
+}
+
+type RecapTotals struct {
+	Posts    int `json:"posts"`
+	Comments int `json:"comments"`
+	Authors  int `json:"authors"`
+}
+
+type RecapContributor struct {
+	User          string `json:"user"`
+	PostCount     int    `json:"post_count"`
+	TotalScore    int    `json:"total_score"`
+	TotalComments int    `json:"total_comments"`
+	Impact        int    `json:"impact"`
+	ActiveMonths  int    `json:"active_months"`
+}
+
+type RecapMentions struct {
+	Posts         int
+	ScoreTotal    int
+	CommentsTotal int
+}
+
+type UserRecapPoll struct {
+	ID        int    `json:"id"`
+	Subreddit string `json:"subreddit"`
+	Title     string `json:"title"`
+	Votes     int    `json:"votes"`
+}
+
+// UserRecapVotes are the poll votes of a user in a sub. WithLeader counts
+// the votes for the option leading the poll.
+type UserRecapVotes struct {
+	Subreddit  string `json:"subreddit"`
+	Votes      int    `json:"votes"`
+	WithLeader int    `json:"with_leader"`
+}
+
+type UserRecapSurveys struct {
+	Created           int `json:"created"`
+	ResponsesReceived int `json:"responses_received"`
+	Answered          int `json:"answered"`
+}
+
+func (r RecapsModel) UpsertRecap(recap Recap) error {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	query := UpsertRecapQuery
+
+	_, err := r.DB.Exec(ctx, query, recap.Subreddit, recap.Year, recap.Complete, recap.Version, recap.Document)
+	if err != nil {
+		return fmt.Errorf("error in upserting recap; %v", err)
+	}
+
+	return nil
+}
+
+func (r RecapsModel) GetRecap(sub string, year int) (*Recap, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := RecapQuery
+
+	var recap Recap
+	err := r.DB.QueryRow(ctx, query, sub, year).Scan(&recap.ID, &recap.Subreddit, &recap.Year, &recap.Complete, &recap.Version, &recap.Document, &recap.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, ErrRecapNotFound
+		}
+		return nil, fmt.Errorf("error in getting recap; %v", err)
+	}
+
+	return &recap, nil
+}
+
+// GetRecapPosts ranks the posts of the category created in [from, to) by the
+// scoring profile, the best limit of every month when byMonth is set.
+func (r RecapsModel) GetRecapPosts(sub, category string, from, to time.Time, scoring ScoringProfile, limit int, byMonth bool) ([]RecapPost, error) {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	filter, ok := categoryFilters[category]
+	if !ok {
+		return nil, fmt.Errorf("invalid category: %s", category)
+	}
+
+	if err := scoring.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(RecapPostsQuery, filter, scoring.expression(), scoring.direction())
+
+	rows, err := r.DB.Query(ctx, query, sub, from.UTC(), to.UTC(), limit, byMonth)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting recap posts; %v", err)
+	}
+	defer rows.Close()
+
+	posts := []RecapPost{}
+	for rows.Next() {
+		var post RecapPost
+		err = rows.Scan(&post.Month, &post.ID, &post.Title, &post.Author, &post.URL, &post.Upvotes, &post.UpvoteRatio, &post.NumComments, &post.CreatedUTC, &post.CategoryScore)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning recap posts; %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading recap posts; %v", err)
+	}
+
+	return posts, nil
+}
+
+func (r RecapsModel) GetRecapTotals(sub string, from, to time.Time) (RecapTotals, error) {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	query := RecapTotalsQuery
+
+	var totals RecapTotals
+	err := r.DB.QueryRow(ctx, query, sub, from.UTC(), to.UTC()).Scan(&totals.Posts, &totals.Comments, &totals.Authors)
+	if err != nil {
+		return RecapTotals{}, fmt.Errorf("error in getting recap totals; %v", err)
+	}
+
+	return totals, nil
+}
+
+// GetRecapContributors ranks the authors of the sub by the posts they made
+// in [from, to).
+func (r RecapsModel) GetRecapContributors(sub string, from, to time.Time, limit int) ([]RecapContributor, error) {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	query := RecapContributorsQuery
+
+	rows, err := r.DB.Query(ctx, query, sub, from.UTC(), to.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting recap contributors; %v", err)
+	}
+	defer rows.Close()
+
+	contributors := []RecapContributor{}
+	for rows.Next() {
+		var c RecapContributor
+		err = rows.Scan(&c.User, &c.PostCount, &c.TotalScore, &c.TotalComments, &c.Impact, &c.ActiveMonths)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning recap contributors; %v", err)
+		}
+		contributors = append(contributors, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading recap contributors; %v", err)
+	}
+
+	return contributors, nil
+}
+
+// GetRecapMentions totals the posts of the sub created in [from, to) that
+// mention any of the names.
+func (r RecapsModel) GetRecapMentions(sub string, names []string, from, to time.Time) (RecapMentions, error) {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	query := RecapMentionsQuery
+
+	var mentions RecapMentions
+	err := r.DB.QueryRow(ctx, query, sub, from.UTC(), to.UTC(), names).Scan(&mentions.Posts, &mentions.ScoreTotal, &mentions.CommentsTotal)
+	if err != nil {
+		return RecapMentions{}, fmt.Errorf("error in getting recap mentions; %v", err)
+	}
+
+	return mentions, nil
+}
+
+// SnapshotTermMonths stores the term counts of the sub's posts by month,
+// replacing what was stored for the months in [from, to). Posts only last
+// PostRetentionDays, the stored counts let a recap compare with the year
+// before all the same.
+func (r RecapsModel) SnapshotTermMonths(sub string, from, to time.Time) (err error) {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", v)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, DeleteTermMonthsQuery, sub, from.UTC(), to.UTC()); err != nil {
+		err = fmt.Errorf("error in deleting term months; %v", err)
+		return
+	}
+
+	if _, err = tx.Exec(ctx, InsertTermMonthsQuery, sub, from.UTC(), to.UTC()); err != nil {
+		err = fmt.Errorf("error in inserting term months; %v", err)
+		return
+	}
+
+	return nil
+}
+
+// GetRecapTermCounts counts the terms of the sub's posts created in
+// [from, split) and in [split, to), all three month starts. Stored months
+// are counted from the snapshot, so posts past retention still count.
+func (r RecapsModel) GetRecapTermCounts(sub string, from, split, to time.Time) (map[string]int, map[string]int, error) {
+	ctx, cancel := HandleBatchctx()
+	defer cancel()
+
+	query := RecapTermCountsQuery
+
+	rows, err := r.DB.Query(ctx, query, sub, from.UTC(), split.UTC(), to.UTC())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in getting recap term counts; %v", err)
+	}
+	defer rows.Close()
+
+	before := make(map[string]int)
+	after := make(map[string]int)
+	for rows.Next() {
+		var term string
+		var beforeCount, afterCount int
+		err = rows.Scan(&term, &beforeCount, &afterCount)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in scanning recap term counts; %v", err)
+		}
+
+		if beforeCount > 0 {
+			before[term] = beforeCount
+		}
+		if afterCount > 0 {
+			after[term] = afterCount
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error in reading recap term counts; %v", err)
+	}
+
+	return before, after, nil
+}
+
+// GetUserRecapPolls returns the polls the user started in [from, to), most
+// voted first, and how many they started in all.
+func (r RecapsModel) GetUserRecapPolls(redditUID string, from, to time.Time, limit int) ([]UserRecapPoll, int, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UserRecapPollsQuery
+
+	rows, err := r.DB.Query(ctx, query, redditUID, from.UTC(), to.UTC(), limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error in getting user recap polls; %v", err)
+	}
+	defer rows.Close()
+
+	polls := []UserRecapPoll{}
+	total := 0
+	for rows.Next() {
+		var poll UserRecapPoll
+		err = rows.Scan(&total, &poll.ID, &poll.Subreddit, &poll.Title, &poll.Votes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error in scanning user recap polls; %v", err)
+		}
+		polls = append(polls, poll)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error in reading user recap polls; %v", err)
+	}
+
+	return polls, total, nil
+}
+
+func (r RecapsModel) GetUserRecapVotes(redditUID string, from, to time.Time) ([]UserRecapVotes, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UserRecapVotesQuery
+
+	rows, err := r.DB.Query(ctx, query, redditUID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error in getting user recap votes; %v", err)
+	}
+	defer rows.Close()
+
+	votes := []UserRecapVotes{}
+	for rows.Next() {
+		var v UserRecapVotes
+		err = rows.Scan(&v.Subreddit, &v.Votes, &v.WithLeader)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning user recap votes; %v", err)
+		}
+		votes = append(votes, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading user recap votes; %v", err)
+	}
+
+	return votes, nil
+}
+
+func (r RecapsModel) GetUserRecapSurveys(redditUID string, from, to time.Time) (UserRecapSurveys, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UserRecapSurveysQuery
+
+	var surveys UserRecapSurveys
+	err := r.DB.QueryRow(ctx, query, redditUID, from.UTC(), to.UTC()).Scan(&surveys.Created, &surveys.ResponsesReceived, &surveys.Answered)
+	if err != nil {
+		return UserRecapSurveys{}, fmt.Errorf("error in getting user recap surveys; %v", err)
+	}
+
+	return surveys, nil
+}
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// scoringFeatures are the only columns and derived values a scoring profile
+// can use. Profiles come from a config file, so nothing from it ever reaches
+// the SQL except through this list.
+var scoringFeatures = map[string]string{
+	"score":        "score",
+	"upvote_ratio": "upvote_ratio",
+	"comments":     "num_comments",
+	// comments per point of score, high when people argue more than they vote
+	"comment_ratio": "(num_comments::float8 / greatest(abs(score), 1))",
+	// how far the post fell short of unanimous upvotes
+	"ratio_dip": "(1 - upvote_ratio)",
+	// score and comments gathered per hour since it was posted
+	"velocity": "((score + num_comments)::float8 / greatest(extract(epoch from (now() at time zone 'UTC') - created_utc) / 3600, 1))",
+}
+
+const maxScoringWeight = 5
+
+type ScoringTerm struct {
+	Feature string  `json:"feature"`
+	Weight  float64 `json:"weight"`
+}
+
+// ScoringProfile ranks posts by the product of its features, each raised to
+// its weight, so features on very different scales can be combined.
+type ScoringProfile struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Terms       []ScoringTerm `json:"terms"`
+	Ascending   bool          `json:"ascending,omitempty"`
+}
+
+// DefaultScoringProfiles are the formulas the category listings always used,
+// plus the drama index. A config file can override any of them.
+var DefaultScoringProfiles = map[string]ScoringProfile{
+	"top": {
+		Description: "score weighted by upvote ratio",
+		Terms:       []ScoringTerm{{Feature: "score", Weight: 1}, {Feature: "upvote_ratio", Weight: 1}},
+	},
+	"controversial": {
+		Description: "score weighted by the ratio dip and the comments",
+		Terms:       []ScoringTerm{{Feature: "score", Weight: 1}, {Feature: "ratio_dip", Weight: 1}, {Feature: "comments", Weight: 1}},
+	},
+	"hated": {
+		Description: "lowest upvote ratio first",
+		Terms:       []ScoringTerm{{Feature: "upvote_ratio", Weight: 1}},
+		Ascending:   true,
+	},
+	"drama": {
+		Description: "comment to score ratio, ratio dip and velocity",
+		Terms:       []ScoringTerm{{Feature: "comment_ratio", Weight: 1}, {Feature: "ratio_dip", Weight: 1}, {Feature: "velocity", Weight: 0.5}},
+	},
+}
+
+var categoryFilters = map[string]string{
+	"top":                   "category = 'top' and top_and_controversial = false",
+	"controversial":         "category = 'controversial' and top_and_controversial = false",
+	"top_and_controversial": "top_and_controversial = true",
+	"hated":                 "category = 'controversial' and top_and_controversial = false",
+}
+
+// categoryScoring is the profile each category is ranked by when none is asked for.
+var categoryScoring = map[string]string{
+	"top":                   "top",
+	"controversial":         "controversial",
+	"top_and_controversial": "top",
+	"hated":                 "hated",
+}
+
+// CategoryScoring names the profile a category is ranked by by default.
+func CategoryScoring(category string) string {
+	return categoryScoring[category]
+}
+
+func (s ScoringProfile) Validate() error {
+	if len(s.Terms) == 0 {
+		return fmt.Errorf("scoring profile %s has no terms", s.Name)
+	}
+
+	for _, term := range s.Terms {
+		if _, ok := scoringFeatures[term.Feature]; !ok {
+			return fmt.Errorf("scoring profile %s uses unknown feature %s", s.Name, term.Feature)
+		}
+
+		if math.IsNaN(term.Weight) || term.Weight <= 0 || term.Weight > maxScoringWeight {
+			return fmt.Errorf("scoring profile %s weighs %s outside (0, %d]", s.Name, term.Feature, maxScoringWeight)
+		}
+	}
+
+	return nil
+}
+
+// expression turns the profile into SQL. A weight of one keeps the feature as
+// is, so the default profiles rank exactly like the old hardwired formulas.
+func (s ScoringProfile) expression() string {
+	factors := make([]string, len(s.Terms))
+	for i, term := range s.Terms {
+		feature := scoringFeatures[term.Feature]
+		if term.Weight == 1 {
+			factors[i] = feature
+			continue
+		}
+		factors[i] = fmt.Sprintf("power(greatest(%s, 0)::float8, %s)", feature, strconv.FormatFloat(term.Weight, 'f', -1, 64))
+	}
+
+	return strings.Join(factors, " * ")
+}
+
+func (s ScoringProfile) direction() string {
+	if s.Ascending {
+		return "asc"
+	}
+	return "desc"
+}
+
+// LoadScoringProfiles reads profiles from a json object of name to profile on
+// top of the defaults. A missing file just leaves the defaults.
+func LoadScoringProfiles(path string) (map[string]ScoringProfile, error) {
+	profiles := make(map[string]ScoringProfile, len(DefaultScoringProfiles))
+	for name, profile := range DefaultScoringProfiles {
+		profile.Name = name
+		profiles[name] = profile
+	}
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error in reading scoring profiles; %v", err)
+		}
+
+		if err == nil {
+			var configured map[string]ScoringProfile
+			if err := json.Unmarshal(b, &configured); err != nil {
+				return nil, fmt.Errorf("error in parsing scoring profiles; %v", err)
+			}
+
+			for name, profile := range configured {
+				profile.Name = name
+				profiles[name] = profile
+			}
+		}
+	}
+
+	for _, profile := range profiles {
+		if err := profile.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return profiles, nil
+}
+
+// SortedScoringProfiles lists the profiles by name.
+func SortedScoringProfiles(profiles map[string]ScoringProfile) []ScoringProfile {
+	sorted := make([]ScoringProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		sorted = append(sorted, profile)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,43 @@
+package data
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoringProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		terms   []ScoringTerm
+		wantErr bool
+	}{
+		{"single feature", []ScoringTerm{{Feature: "score", Weight: 1}}, false},
+		{"fractional weight", []ScoringTerm{{Feature: "velocity", Weight: 0.5}}, false},
+		{"max weight", []ScoringTerm{{Feature: "comments", Weight: maxScoringWeight}}, false},
+		{"no terms", nil, true},
+		{"unknown feature", []ScoringTerm{{Feature: "score; drop table subreddit_posts", Weight: 1}}, true},
+		{"column name instead of feature", []ScoringTerm{{Feature: "num_comments", Weight: 1}}, true},
+		{"zero weight", []ScoringTerm{{Feature: "score", Weight: 0}}, true},
+		{"negative weight", []ScoringTerm{{Feature: "score", Weight: -1}}, true},
+		{"weight too large", []ScoringTerm{{Feature: "score", Weight: maxScoringWeight + 0.1}}, true},
+		{"nan weight", []ScoringTerm{{Feature: "score", Weight: math.NaN()}}, true},
+		{"one bad term of many", []ScoringTerm{{Feature: "score", Weight: 1}, {Feature: "karma", Weight: 1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ScoringProfile{Name: "test", Terms: tt.terms}.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultScoringProfilesAreValid(t *testing.T) {
+	for name, profile := range DefaultScoringProfiles {
+		if err := profile.Validate(); err != nil {
+			t.Errorf("default profile %s: %v", name, err)
+		}
+	}
+}
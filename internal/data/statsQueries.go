@@ -0,0 +1,35 @@
+package data
+
+const (
+	UpsertSubredditStatsQuery = `
+	INSERT INTO subreddit_stats (
+		subreddit,
+		day,
+		subscribers,
+		active_users,
+		posts_ingested
+	)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (subreddit, day) DO
+	UPDATE
+	SET
+		subscribers = EXCLUDED.subscribers,
+		-- reddit doesn't always report the active users
+		active_users = COALESCE(EXCLUDED.active_users, subreddit_stats.active_users),
+		posts_ingested = EXCLUDED.posts_ingested,
+		updated_at = NOW()
+	`
+
+	SubredditStatsQuery = `
+	SELECT subreddit,
+		day,
+		subscribers,
+		active_users,
+		posts_ingested
+	FROM subreddit_stats
+	WHERE subreddit = ANY($1)
+		AND day >= $2::date
+		AND day < $3::date
+	ORDER BY subreddit ASC, day ASC
+	`
+)
@@ -0,0 +1,200 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StoriesModel struct {
+	DB *pgx.Pool
+}
+
+// StoryCandidate is a stored post that shares a band with the post being
+// clustered.
+type StoryCandidate struct {
+	PostID    string
+	ClusterID int
+	Signature []int64
+}
+
+type StoryPost struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	URL         string    `json:"url"`
+	Upvotes     int       `json:"upvotes"`
+	NumComments int       `json:"num_comments"`
+	CreatedUTC  time.Time `json:"created_utc"`
+	Similarity  float64   `json:"similarity"`
+}
+
+// Story is a cluster of posts about the same thing, led by the first post.
+type Story struct {
+	ID            int         `json:"id"`
+	Subreddit     string      `json:"subreddit"`
+	Title         string      `json:"title"`
+	FirstPostID   string      `json:"first_post_id"`
+	FirstAuthor   string      `json:"first_author"`
+	FirstSeen     time.Time   `json:"first_seen"`
+	LastSeen      time.Time   `json:"last_seen"`
+	PostCount     int         `json:"post_count"`
+	AuthorCount   int         `json:"author_count"`
+	ScoreTotal    int         `json:"score_total"`
+	CommentsTotal int         `json:"comments_total"`
+	Posts         []StoryPost `json:"posts"`
+}
+
+// GetSignedPosts returns which of the posts already belong to a story.
+func (s StoriesModel) GetSignedPosts(ids []string) (map[string]bool, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := SignedPostsQuery
+
+	rows, err := s.DB.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting signed posts; %v", err)
+	}
+	defer rows.Close()
+
+	signed := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error in scanning signed posts; %v", err)
+		}
+		signed[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading signed posts; %v", err)
+	}
+
+	return signed, nil
+}
+
+// GetStoryCandidates returns the posts of the sub created in [from, to) that
+// fall in any of the bands.
+func (s StoriesModel) GetStoryCandidates(sub string, bands []string, from, to time.Time) ([]StoryCandidate, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := StoryCandidatesQuery
+
+	rows, err := s.DB.Query(ctx, query, sub, bands, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error in getting story candidates; %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []StoryCandidate
+	for rows.Next() {
+		var candidate StoryCandidate
+		err = rows.Scan(&candidate.PostID, &candidate.ClusterID, &candidate.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning story candidates; %v", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading story candidates; %v", err)
+	}
+
+	return candidates, nil
+}
+
+// AssignStory puts the post in the story clusterID, or in a story of its own
+// when clusterID is 0, and returns the story it ended up in.
+func (s StoriesModel) AssignStory(post Post, clusterID int, signature []int64, bands []string, similarity float64) (id int, err error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", r)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	if clusterID == 0 {
+		err = tx.QueryRow(ctx, InsertStoryClusterQuery, post.Subreddit, post.ID, post.Author, post.Title, post.CreatedUTC.UTC()).Scan(&clusterID)
+		if err != nil {
+			err = fmt.Errorf("error in inserting story; %v", err)
+			return
+		}
+	} else {
+		_, err = tx.Exec(ctx, JoinStoryClusterQuery, clusterID, post.CreatedUTC.UTC(), post.ID, post.Author, post.Title)
+		if err != nil {
+			err = fmt.Errorf("error in updating story; %v", err)
+			return
+		}
+	}
+
+	_, err = tx.Exec(ctx, InsertPostSignatureQuery, post.ID, clusterID, signature, bands, similarity)
+	if err != nil {
+		err = fmt.Errorf("error in inserting post signature; %v", err)
+		return
+	}
+
+	return clusterID, nil
+}
+
+// storiesOrderBy can't use the totals by their names, postgres only allows
+// output names as a whole sort key, not inside an expression.
+func storiesOrderBy(filters Filters) string {
+	switch filters.SortColumn() {
+	case "engagement":
+		return "COALESCE(SUM(p.score), 0) + COALESCE(SUM(p.num_comments), 0) DESC, c.last_seen DESC"
+	case "recent":
+		return "c.last_seen DESC"
+	default:
+		return "post_count DESC, c.last_seen DESC"
+	}
+}
+
+// GetStories lists the stories of the sub active in [from, to) that have at
+// least minPosts posts. With a type only the posts of that type are counted.
+func (s StoriesModel) GetStories(sub, postType string, from, to time.Time, minPosts int, filters Filters) ([]Story, Metadata, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := fmt.Sprintf(GetStoriesQuery, storiesOrderBy(filters))
+
+	rows, err := s.DB.Query(ctx, query, sub, from.UTC(), to.UTC(), minPosts, filters.limit(), filters.offset(), postType)
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in getting stories; %v", err)
+	}
+	defer rows.Close()
+
+	stories := []Story{}
+	totalRecords := 0
+	for rows.Next() {
+		var story Story
+		err = rows.Scan(&totalRecords, &story.ID, &story.Subreddit, &story.Title, &story.FirstPostID, &story.FirstAuthor, &story.FirstSeen, &story.LastSeen, &story.PostCount, &story.AuthorCount, &story.ScoreTotal, &story.CommentsTotal, &story.Posts)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error in scanning stories; %v", err)
+		}
+		stories = append(stories, story)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in iterating stories; %v", err)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return stories, metadata, nil
+}
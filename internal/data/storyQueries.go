@@ -0,0 +1,108 @@
+package data
+
+const (
+	SignedPostsQuery = `
+	SELECT post_id
+	FROM post_signatures
+	WHERE post_id = ANY($1)
+	`
+
+	StoryCandidatesQuery = `
+	SELECT s.post_id,
+		s.cluster_id,
+		s.signature
+	FROM post_signatures s
+	JOIN subreddit_posts p ON p.id = s.post_id
+	WHERE p.subreddit = $1
+		AND s.bands && $2::text[]
+		AND p.created_utc >= $3
+		AND p.created_utc < $4
+	`
+
+	InsertStoryClusterQuery = `
+	INSERT INTO story_clusters (
+		subreddit,
+		first_post_id,
+		first_author,
+		title,
+		first_seen,
+		last_seen
+	)
+	VALUES ($1, $2, $3, $4, $5, $5)
+	RETURNING id
+	`
+
+	// JoinStoryClusterQuery moves the first post of the cluster to the new
+	// post when it turns out to be older, which happens when posts come in
+	// out of order.
+	JoinStoryClusterQuery = `
+	UPDATE story_clusters
+	SET
+		first_post_id = CASE WHEN $2::timestamp < first_seen THEN $3 ELSE first_post_id END,
+		first_author = CASE WHEN $2::timestamp < first_seen THEN $4 ELSE first_author END,
+		title = CASE WHEN $2::timestamp < first_seen THEN $5 ELSE title END,
+		first_seen = LEAST(first_seen, $2::timestamp),
+		last_seen = GREATEST(last_seen, $2::timestamp)
+	WHERE id = $1
+	`
+
+	InsertPostSignatureQuery = `
+	INSERT INTO post_signatures (
+		post_id,
+		cluster_id,
+		signature,
+		bands,
+		similarity
+	)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (post_id) DO NOTHING
+	`
+
+	DeleteEmptyStoryClustersQuery = `
+	DELETE FROM story_clusters c
+	WHERE NOT EXISTS (
+		SELECT 1
+		FROM post_signatures s
+		WHERE s.cluster_id = c.id
+	)
+	`
+
+	// GetStoriesQuery only counts the posts of the type in $7, or of every
+	// type when it is empty.
+	GetStoriesQuery = `
+	SELECT COUNT(*) OVER () AS total,
+		c.id,
+		c.subreddit,
+		c.title,
+		c.first_post_id,
+		c.first_author,
+		c.first_seen,
+		c.last_seen,
+		COUNT(*) AS post_count,
+		COUNT(DISTINCT p.author) AS author_count,
+		COALESCE(SUM(p.score), 0) AS score_total,
+		COALESCE(SUM(p.num_comments), 0) AS comments_total,
+		json_agg(json_build_object(
+			'id', p.id,
+			'title', p.title,
+			'author', p.author,
+			'url', p.permalink,
+			'upvotes', p.score,
+			'num_comments', p.num_comments,
+			'created_utc', p.created_utc AT TIME ZONE 'UTC',
+			'similarity', s.similarity
+		) ORDER BY p.created_utc ASC) AS posts
+	FROM story_clusters c
+	JOIN post_signatures s ON s.cluster_id = c.id
+	JOIN subreddit_posts p ON p.id = s.post_id
+	WHERE c.subreddit = $1
+		AND c.last_seen >= $2
+		AND c.first_seen < $3
+		AND ($7::text = '' OR p.post_type = $7)
+	GROUP BY c.id
+	HAVING COUNT(*) >= $4
+	ORDER BY %s
+	LIMIT $5
+	OFFSET $6
+	`
+)
@@ -0,0 +1,62 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StatsModel struct {
+	DB *pgx.Pool
+}
+
+// SubredditStat is a daily snapshot of a sub, taken by the ingestion job.
+type SubredditStat struct {
+	Subreddit     string    `json:"subreddit"`
+	Day           time.Time `json:"day"`
+	Subscribers   int       `json:"subscribers"`
+	ActiveUsers   *int      `json:"active_users"`
+	PostsIngested int       `json:"posts_ingested"`
+}
+
+func (s StatsModel) UpsertSubredditStat(stat SubredditStat) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UpsertSubredditStatsQuery
+
+	_, err := s.DB.Exec(ctx, query, stat.Subreddit, stat.Day.Format(time.DateOnly), stat.Subscribers, stat.ActiveUsers, stat.PostsIngested)
+	if err != nil {
+		return fmt.Errorf("error in inserting subreddit stats; %v", err)
+	}
+
+	return nil
+}
+
+// GetSubredditStats returns the daily snapshots of the subs in [from, to),
+// ordered by sub and day.
+func (s StatsModel) GetSubredditStats(subs []string, from, to time.Time) ([]SubredditStat, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := SubredditStatsQuery
+
+	rows, err := s.DB.Query(ctx, query, subs, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("error in getting subreddit stats; %v", err)
+	}
+	defer rows.Close()
+
+	var stats []SubredditStat
+	for rows.Next() {
+		var stat SubredditStat
+		err = rows.Scan(&stat.Subreddit, &stat.Day, &stat.Subscribers, &stat.ActiveUsers, &stat.PostsIngested)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning subreddit stats; %v", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
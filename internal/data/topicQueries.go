@@ -0,0 +1,66 @@
+package data
+
+const (
+	// MonthPostTermsQuery takes the type in $4, an empty type is every type.
+	MonthPostTermsQuery = `
+	SELECT p.id,
+		p.title,
+		p.author,
+		p.permalink,
+		p.score,
+		p.num_comments,
+		pt.term,
+		pt.count
+	FROM subreddit_posts p
+	JOIN post_terms pt ON pt.post_id = p.id
+	WHERE p.subreddit = $1
+		AND p.created_utc >= $2
+		AND p.created_utc < $3
+		AND ($4::text = '' OR p.post_type = $4)
+	ORDER BY p.id
+	`
+
+	DeleteTopicsQuery = `
+	DELETE FROM topics
+	WHERE subreddit = $1
+		AND month = $2::date
+		AND post_type = $3
+	`
+
+	InsertTopicQuery = `
+	INSERT INTO topics (
+		subreddit,
+		month,
+		rank,
+		label,
+		terms,
+		posts,
+		post_count,
+		share,
+		score_total,
+		comments_total,
+		post_type
+	)
+	VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	GetTopicsQuery = `
+	SELECT id,
+		subreddit,
+		month,
+		rank,
+		label,
+		terms,
+		posts,
+		post_count,
+		share,
+		score_total,
+		comments_total
+	FROM topics
+	WHERE subreddit = $1
+		AND month >= $2::date
+		AND month < $3::date
+		AND post_type = $4
+	ORDER BY month ASC, rank ASC
+	`
+)
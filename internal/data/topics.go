@@ -0,0 +1,149 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+	"github.com/priyankishorems/bollytics-go/internal/topics"
+)
+
+type TopicsModel struct {
+	DB *pgx.Pool
+}
+
+// TopicDocument is a post of the month with its terms, ready to be clustered.
+type TopicDocument struct {
+	Post  TopicPost
+	Terms map[string]int
+}
+
+type TopicPost struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Author      string  `json:"author"`
+	URL         string  `json:"url"`
+	Upvotes     int     `json:"upvotes"`
+	NumComments int     `json:"num_comments"`
+	Similarity  float64 `json:"similarity"`
+}
+
+type Topic struct {
+	ID            int                 `json:"id"`
+	Subreddit     string              `json:"subreddit"`
+	Month         time.Time           `json:"month"`
+	Rank          int                 `json:"rank"`
+	Label         string              `json:"label"`
+	Terms         []topics.TermWeight `json:"terms"`
+	Posts         []TopicPost         `json:"posts"`
+	PostCount     int                 `json:"post_count"`
+	Share         float64             `json:"share"`
+	ScoreTotal    int                 `json:"score_total"`
+	CommentsTotal int                 `json:"comments_total"`
+}
+
+// GetMonthDocuments returns the posts of [from, to) of the type that have
+// terms, of every type when postType is empty.
+func (t TopicsModel) GetMonthDocuments(sub, postType string, from, to time.Time) ([]TopicDocument, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := MonthPostTermsQuery
+
+	rows, err := t.DB.Query(ctx, query, sub, from.UTC(), to.UTC(), postType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting month documents; %v", err)
+	}
+	defer rows.Close()
+
+	var docs []TopicDocument
+	for rows.Next() {
+		var post TopicPost
+		var term string
+		var count int
+		err = rows.Scan(&post.ID, &post.Title, &post.Author, &post.URL, &post.Upvotes, &post.NumComments, &term, &count)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning month documents; %v", err)
+		}
+
+		// the rows come ordered by post, so a new id starts a new document
+		if len(docs) == 0 || docs[len(docs)-1].Post.ID != post.ID {
+			docs = append(docs, TopicDocument{Post: post, Terms: make(map[string]int)})
+		}
+		docs[len(docs)-1].Terms[term] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in iterating month documents; %v", err)
+	}
+
+	return docs, nil
+}
+
+// ReplaceTopics swaps the stored topics of a sub's month and type for these.
+// The topics of every type are stored under the empty type.
+func (t TopicsModel) ReplaceTopics(sub, postType string, month time.Time, monthTopics []Topic) (err error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	tx, err := t.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", r)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	day := month.Format(time.DateOnly)
+
+	if _, err = tx.Exec(ctx, DeleteTopicsQuery, sub, day, postType); err != nil {
+		err = fmt.Errorf("error in deleting topics; %v", err)
+		return
+	}
+
+	for _, topic := range monthTopics {
+		_, err = tx.Exec(ctx, InsertTopicQuery, sub, day, topic.Rank, topic.Label, topic.Terms, topic.Posts, topic.PostCount, topic.Share, topic.ScoreTotal, topic.CommentsTotal, postType)
+		if err != nil {
+			err = fmt.Errorf("error in inserting topic; %v", err)
+			return
+		}
+	}
+
+	return nil
+}
+
+// GetTopics returns the topics of the type of the months in [from, to), by
+// month and rank.
+func (t TopicsModel) GetTopics(sub, postType string, from, to time.Time) ([]Topic, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := GetTopicsQuery
+
+	rows, err := t.DB.Query(ctx, query, sub, from.Format(time.DateOnly), to.Format(time.DateOnly), postType)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting topics; %v", err)
+	}
+	defer rows.Close()
+
+	var result []Topic
+	for rows.Next() {
+		var topic Topic
+		err = rows.Scan(&topic.ID, &topic.Subreddit, &topic.Month, &topic.Rank, &topic.Label, &topic.Terms, &topic.Posts, &topic.PostCount, &topic.Share, &topic.ScoreTotal, &topic.CommentsTotal)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning topics; %v", err)
+		}
+		result = append(result, topic)
+	}
+
+	return result, nil
+}
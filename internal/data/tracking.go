@@ -0,0 +1,258 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pg "github.com/jackc/pgx/v5"
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrTrackingWindowNotFound = errors.New("tracking window not found")
+
+type TrackingModel struct {
+	DB *pgx.Pool
+}
+
+// TrackingWindow is a stretch of time, usually around a release, during
+// which the sub's new and hot posts are polled for the keywords.
+type TrackingWindow struct {
+	ID             int        `json:"id"`
+	Subreddit      string     `json:"subreddit" validate:"required"`
+	Title          string     `json:"title" validate:"required,max=255"`
+	TMDBID         *int       `json:"tmdb_id" validate:"omitempty,gte=1"`
+	Keywords       []string   `json:"keywords" validate:"required,min=1,max=20,dive,min=2,max=100"`
+	StartTime      time.Time  `json:"start_time" validate:"required"`
+	EndTime        time.Time  `json:"end_time" validate:"required"`
+	CreatedBy      string     `json:"-"`
+	Posts          int        `json:"posts"`
+	Snapshots      int        `json:"snapshots"`
+	LastCapturedAt *time.Time `json:"last_captured_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TrackingSnapshot is a matching post as it stood when it was polled.
+type TrackingSnapshot struct {
+	WindowID    int
+	PostID      string
+	Title       string
+	Author      string
+	Permalink   string
+	Score       int
+	NumComments int
+	UpvoteRatio float64
+	CreatedUTC  time.Time
+	InNew       bool
+	InHot       bool
+	CapturedAt  time.Time
+}
+
+type TrackingPoint struct {
+	Bucket      time.Time `json:"bucket"`
+	Posts       int       `json:"posts"`
+	ActivePosts int       `json:"active_posts"`
+	Comments    int       `json:"comments"`
+	Score       int       `json:"score"`
+}
+
+type TrackedPost struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	URL         string    `json:"url"`
+	Upvotes     int       `json:"upvotes"`
+	NumComments int       `json:"num_comments"`
+	UpvoteRatio float64   `json:"upvote_ratio"`
+	CreatedUTC  time.Time `json:"created_utc"`
+	InHot       bool      `json:"in_hot"`
+	CapturedAt  time.Time `json:"captured_at"`
+}
+
+func scanTrackingWindow(row pg.Row) (*TrackingWindow, error) {
+	var w TrackingWindow
+	err := row.Scan(&w.ID, &w.Subreddit, &w.Title, &w.TMDBID, &w.Keywords, &w.StartTime, &w.EndTime, &w.CreatedAt, &w.Posts, &w.Snapshots, &w.LastCapturedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// GetWindows lists every window, newest first, or only the running ones when
+// activeOnly is set.
+func (t TrackingModel) GetWindows(activeOnly bool) ([]TrackingWindow, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := GetTrackingWindowsQuery
+
+	rows, err := t.DB.Query(ctx, query, time.Now().UTC(), activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting tracking windows; %v", err)
+	}
+	defer rows.Close()
+
+	windows := []TrackingWindow{}
+	for rows.Next() {
+		w, err := scanTrackingWindow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning tracking windows; %v", err)
+		}
+		windows = append(windows, *w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading tracking windows; %v", err)
+	}
+
+	return windows, nil
+}
+
+func (t TrackingModel) GetWindow(id int) (*TrackingWindow, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := GetTrackingWindowQuery
+
+	w, err := scanTrackingWindow(t.DB.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return nil, ErrTrackingWindowNotFound
+		}
+		return nil, fmt.Errorf("error in getting tracking window; %v", err)
+	}
+
+	return w, nil
+}
+
+func (t TrackingModel) InsertWindow(w *TrackingWindow) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := InsertTrackingWindowQuery
+
+	err := t.DB.QueryRow(ctx, query, w.Subreddit, w.Title, w.TMDBID, w.Keywords, w.StartTime.UTC(), w.EndTime.UTC(), w.CreatedBy).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error in inserting tracking window; %v", err)
+	}
+
+	return nil
+}
+
+func (t TrackingModel) UpdateWindow(w *TrackingWindow) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UpdateTrackingWindowQuery
+
+	err := t.DB.QueryRow(ctx, query, w.ID, w.Subreddit, w.Title, w.TMDBID, w.Keywords, w.StartTime.UTC(), w.EndTime.UTC()).Scan(&w.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return ErrTrackingWindowNotFound
+		}
+		return fmt.Errorf("error in updating tracking window; %v", err)
+	}
+
+	return nil
+}
+
+func (t TrackingModel) DeleteWindow(id int) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DeleteTrackingWindowQuery
+
+	tag, err := t.DB.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error in deleting tracking window; %v", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrTrackingWindowNotFound
+	}
+
+	return nil
+}
+
+func (t TrackingModel) InsertSnapshots(snapshots []TrackingSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	rows := make([][]any, len(snapshots))
+	for i, s := range snapshots {
+		rows[i] = []any{s.WindowID, s.PostID, s.Title, s.Author, s.Permalink, s.Score, s.NumComments, s.UpvoteRatio, s.CreatedUTC.UTC(), s.InNew, s.InHot, s.CapturedAt.UTC()}
+	}
+
+	columns := []string{"window_id", "post_id", "title", "author", "permalink", "score", "num_comments", "upvote_ratio", "created_utc", "in_new", "in_hot", "captured_at"}
+
+	_, err := t.DB.CopyFrom(ctx, pg.Identifier{"tracking_snapshots"}, columns, pg.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("error in inserting tracking snapshots; %v", err)
+	}
+
+	return nil
+}
+
+// GetVolume returns the window's activity in buckets of the given size.
+func (t TrackingModel) GetVolume(id int, bucket time.Duration) ([]TrackingPoint, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := TrackingVolumeQuery
+
+	rows, err := t.DB.Query(ctx, query, id, int(bucket.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("error in getting tracking volume; %v", err)
+	}
+	defer rows.Close()
+
+	points := []TrackingPoint{}
+	for rows.Next() {
+		var p TrackingPoint
+		err = rows.Scan(&p.Bucket, &p.Posts, &p.ActivePosts, &p.Comments, &p.Score)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning tracking volume; %v", err)
+		}
+		points = append(points, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading tracking volume; %v", err)
+	}
+
+	return points, nil
+}
+
+func (t TrackingModel) GetTopPosts(id, limit int) ([]TrackedPost, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := TrackingTopPostsQuery
+
+	rows, err := t.DB.Query(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting tracked posts; %v", err)
+	}
+	defer rows.Close()
+
+	posts := []TrackedPost{}
+	for rows.Next() {
+		var p TrackedPost
+		err = rows.Scan(&p.ID, &p.Title, &p.Author, &p.URL, &p.Upvotes, &p.NumComments, &p.UpvoteRatio, &p.CreatedUTC, &p.InHot, &p.CapturedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning tracked posts; %v", err)
+		}
+		posts = append(posts, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in reading tracked posts; %v", err)
+	}
+
+	return posts, nil
+}
@@ -0,0 +1,132 @@
+package data
+
+const (
+	// GetTrackingWindowsQuery lists the windows with how much was captured
+	// for them, only the ones running at $1 when $2 is set.
+	GetTrackingWindowsQuery = `
+	SELECT w.id,
+		w.subreddit,
+		w.title,
+		w.tmdb_id,
+		w.keywords,
+		w.start_time,
+		w.end_time,
+		w.created_at,
+		COUNT(DISTINCT s.post_id) AS posts,
+		COUNT(DISTINCT s.captured_at) AS snapshots,
+		MAX(s.captured_at) AS last_captured_at
+	FROM tracking_windows w
+	LEFT JOIN tracking_snapshots s ON s.window_id = w.id
+	WHERE NOT $2::bool
+		OR (w.start_time <= $1 AND w.end_time > $1)
+	GROUP BY w.id
+	ORDER BY w.start_time DESC, w.id DESC
+	`
+
+	GetTrackingWindowQuery = `
+	SELECT w.id,
+		w.subreddit,
+		w.title,
+		w.tmdb_id,
+		w.keywords,
+		w.start_time,
+		w.end_time,
+		w.created_at,
+		COUNT(DISTINCT s.post_id) AS posts,
+		COUNT(DISTINCT s.captured_at) AS snapshots,
+		MAX(s.captured_at) AS last_captured_at
+	FROM tracking_windows w
+	LEFT JOIN tracking_snapshots s ON s.window_id = w.id
+	WHERE w.id = $1
+	GROUP BY w.id
+	`
+
+	InsertTrackingWindowQuery = `
+	INSERT INTO tracking_windows (subreddit, title, tmdb_id, keywords, start_time, end_time, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at
+	`
+
+	UpdateTrackingWindowQuery = `
+	UPDATE tracking_windows
+	SET subreddit = $2,
+		title = $3,
+		tmdb_id = $4,
+		keywords = $5,
+		start_time = $6,
+		end_time = $7
+	WHERE id = $1
+	RETURNING created_at
+	`
+
+	DeleteTrackingWindowQuery = `
+	DELETE FROM tracking_windows
+	WHERE id = $1
+	`
+
+	// TrackingVolumeQuery buckets the window's activity into $2 seconds.
+	// Comments and score are what the tracked posts gained since they were
+	// last captured, the whole count the first time a post is seen. Posts are
+	// the matching posts created in the bucket.
+	TrackingVolumeQuery = `
+	WITH deltas AS (
+		SELECT post_id,
+			captured_at,
+			num_comments - COALESCE(LAG(num_comments) OVER w, 0) AS new_comments,
+			score - COALESCE(LAG(score) OVER w, 0) AS new_score
+		FROM tracking_snapshots
+		WHERE window_id = $1
+		WINDOW w AS (PARTITION BY post_id ORDER BY captured_at)
+	),
+	activity AS (
+		SELECT to_timestamp(floor(extract(epoch FROM captured_at) / $2::int) * $2::int) AS bucket,
+			COUNT(DISTINCT post_id) AS active_posts,
+			SUM(new_comments) AS comments,
+			SUM(new_score) AS score
+		FROM deltas
+		GROUP BY bucket
+	),
+	created AS (
+		SELECT to_timestamp(floor(extract(epoch FROM created_utc) / $2::int) * $2::int) AS bucket,
+			COUNT(*) AS posts
+		FROM (
+			SELECT DISTINCT post_id,
+				created_utc
+			FROM tracking_snapshots
+			WHERE window_id = $1
+		) p
+		GROUP BY bucket
+	)
+	SELECT COALESCE(a.bucket, c.bucket) AS bucket,
+		COALESCE(c.posts, 0) AS posts,
+		COALESCE(a.active_posts, 0) AS active_posts,
+		COALESCE(a.comments, 0) AS comments,
+		COALESCE(a.score, 0) AS score
+	FROM activity a
+	FULL JOIN created c ON c.bucket = a.bucket
+	ORDER BY bucket ASC
+	`
+
+	// TrackingTopPostsQuery ranks the window's posts by their comments as
+	// last captured.
+	TrackingTopPostsQuery = `
+	SELECT post_id,
+		title,
+		author,
+		permalink,
+		score,
+		num_comments,
+		upvote_ratio,
+		created_utc,
+		in_hot,
+		captured_at
+	FROM (
+		SELECT DISTINCT ON (post_id) *
+		FROM tracking_snapshots
+		WHERE window_id = $1
+		ORDER BY post_id, captured_at DESC
+	) latest
+	ORDER BY num_comments DESC, score DESC
+	LIMIT $2
+	`
+)
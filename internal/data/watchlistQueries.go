@@ -0,0 +1,117 @@
+package data
+
+const (
+	GetWatchlistsQuery = `
+	SELECT w.id,
+		w.keyword,
+		w.subreddit,
+		w.created_at,
+		COUNT(m.post_id) AS matches,
+		COUNT(m.post_id) FILTER (WHERE m.read_at IS NULL) AS unread
+	FROM watchlists w
+	LEFT JOIN watchlist_matches m ON m.watchlist_id = w.id
+	WHERE w.reddit_uid = $1
+	GROUP BY w.id
+	ORDER BY w.created_at DESC, w.id DESC
+	`
+
+	CountWatchlistsQuery = `
+	SELECT COUNT(*)
+	FROM watchlists
+	WHERE reddit_uid = $1
+	`
+
+	InsertWatchlistQuery = `
+	INSERT INTO watchlists (reddit_uid, keyword, subreddit)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at
+	`
+
+	UpdateWatchlistQuery = `
+	UPDATE watchlists
+	SET keyword = $3,
+		subreddit = $4
+	WHERE id = $1
+		AND reddit_uid = $2
+	RETURNING created_at
+	`
+
+	DeleteWatchlistQuery = `
+	DELETE FROM watchlists
+	WHERE id = $1
+		AND reddit_uid = $2
+	`
+
+	DeleteWatchlistMatchesQuery = `
+	DELETE FROM watchlist_matches
+	WHERE watchlist_id = $1
+	`
+
+	// BackfillWatchlistQuery matches a single watchlist against the posts
+	// created since $2, so a new keyword doesn't start with an empty feed.
+	BackfillWatchlistQuery = `
+	INSERT INTO watchlist_matches (watchlist_id, post_id)
+	SELECT w.id,
+		p.id
+	FROM watchlists w
+	JOIN subreddit_posts p ON (w.subreddit = '' OR p.subreddit = w.subreddit)
+		AND p.search_vector @@ phraseto_tsquery('english', w.keyword)
+	WHERE w.id = $1
+		AND p.created_utc >= $2
+	ON CONFLICT (watchlist_id, post_id) DO NOTHING
+	`
+
+	MatchWatchlistsQuery = `
+	INSERT INTO watchlist_matches (watchlist_id, post_id)
+	SELECT w.id,
+		p.id
+	FROM subreddit_posts p
+	JOIN watchlists w ON (w.subreddit = '' OR p.subreddit = w.subreddit)
+		AND p.search_vector @@ phraseto_tsquery('english', w.keyword)
+	WHERE p.id = ANY($1::text[])
+	ON CONFLICT (watchlist_id, post_id) DO NOTHING
+	`
+
+	WatchlistFeedQuery = `
+	SELECT COUNT(*) OVER () AS total,
+		p.id,
+		p.title,
+		p.author,
+		p.permalink,
+		p.subreddit,
+		p.score,
+		p.num_comments,
+		p.created_utc,
+		array_agg(w.keyword ORDER BY w.keyword) AS keywords,
+		MIN(m.matched_at) AS matched_at,
+		bool_and(m.read_at IS NOT NULL) AS read
+	FROM watchlist_matches m
+	JOIN watchlists w ON w.id = m.watchlist_id
+	JOIN subreddit_posts p ON p.id = m.post_id
+	WHERE w.reddit_uid = $1
+		AND ($2::int = 0 OR w.id = $2)
+	GROUP BY p.id
+	HAVING NOT $3::bool OR bool_or(m.read_at IS NULL)
+	ORDER BY MIN(m.matched_at) DESC, p.created_utc DESC
+	LIMIT $4
+	OFFSET $5
+	`
+
+	UnreadMatchesQuery = `
+	SELECT COUNT(DISTINCT m.post_id)
+	FROM watchlist_matches m
+	JOIN watchlists w ON w.id = m.watchlist_id
+	WHERE w.reddit_uid = $1
+		AND m.read_at IS NULL
+	`
+
+	MarkMatchesReadQuery = `
+	UPDATE watchlist_matches m
+	SET read_at = NOW()
+	FROM watchlists w
+	WHERE w.id = m.watchlist_id
+		AND w.reddit_uid = $1
+		AND m.read_at IS NULL
+		AND (COALESCE(cardinality($2::text[]), 0) = 0 OR m.post_id = ANY($2::text[]))
+	`
+)
@@ -0,0 +1,283 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pg "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pgx "github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrWatchlistNotFound  = errors.New("watchlist not found")
+	ErrDuplicateWatchlist = errors.New("keyword already on the watchlist")
+)
+
+type WatchlistsModel struct {
+	DB *pgx.Pool
+}
+
+// Watchlist is a keyword or phrase a user follows, in one sub or in all of
+// them when Subreddit is empty.
+type Watchlist struct {
+	ID        int       `json:"id"`
+	RedditUID string    `json:"-"`
+	Keyword   string    `json:"keyword" validate:"required,min=2,max=100"`
+	Subreddit string    `json:"subreddit"`
+	Matches   int       `json:"matches"`
+	Unread    int       `json:"unread"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WatchlistPost struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	URL         string    `json:"url"`
+	Subreddit   string    `json:"subreddit"`
+	Upvotes     int       `json:"upvotes"`
+	NumComments int       `json:"num_comments"`
+	CreatedUTC  time.Time `json:"created_utc"`
+	Keywords    []string  `json:"keywords"`
+	MatchedAt   time.Time `json:"matched_at"`
+	Read        bool      `json:"read"`
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (w WatchlistsModel) GetWatchlists(redditUID string) ([]Watchlist, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := GetWatchlistsQuery
+
+	rows, err := w.DB.Query(ctx, query, redditUID)
+	if err != nil {
+		return nil, fmt.Errorf("error in getting watchlists; %v", err)
+	}
+	defer rows.Close()
+
+	watchlists := []Watchlist{}
+	for rows.Next() {
+		watchlist := Watchlist{RedditUID: redditUID}
+		err = rows.Scan(&watchlist.ID, &watchlist.Keyword, &watchlist.Subreddit, &watchlist.CreatedAt, &watchlist.Matches, &watchlist.Unread)
+		if err != nil {
+			return nil, fmt.Errorf("error in scanning watchlists; %v", err)
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+
+	return watchlists, nil
+}
+
+func (w WatchlistsModel) CountWatchlists(redditUID string) (int, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := CountWatchlistsQuery
+
+	var count int
+	if err := w.DB.QueryRow(ctx, query, redditUID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error in counting watchlists; %v", err)
+	}
+
+	return count, nil
+}
+
+// InsertWatchlist stores the watchlist and matches it against the posts
+// created since backfillFrom.
+func (w WatchlistsModel) InsertWatchlist(watchlist *Watchlist, backfillFrom time.Time) (err error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	tx, err := w.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", r)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	err = tx.QueryRow(ctx, InsertWatchlistQuery, watchlist.RedditUID, watchlist.Keyword, watchlist.Subreddit).Scan(&watchlist.ID, &watchlist.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			err = ErrDuplicateWatchlist
+			return
+		}
+		err = fmt.Errorf("error in inserting watchlist; %v", err)
+		return
+	}
+
+	tag, err := tx.Exec(ctx, BackfillWatchlistQuery, watchlist.ID, backfillFrom.UTC())
+	if err != nil {
+		err = fmt.Errorf("error in matching watchlist; %v", err)
+		return
+	}
+
+	watchlist.Matches = int(tag.RowsAffected())
+	watchlist.Unread = watchlist.Matches
+
+	return nil
+}
+
+// UpdateWatchlist changes the keyword or sub of a user's watchlist. The old
+// matches no longer apply, so it is matched again from backfillFrom.
+func (w WatchlistsModel) UpdateWatchlist(watchlist *Watchlist, backfillFrom time.Time) (err error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	tx, err := w.DB.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error in starting transaction; %v", err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback(ctx)
+			err = fmt.Errorf("transaction panicked: %v", r)
+		} else if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	err = tx.QueryRow(ctx, UpdateWatchlistQuery, watchlist.ID, watchlist.RedditUID, watchlist.Keyword, watchlist.Subreddit).Scan(&watchlist.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, pg.ErrNoRows):
+			err = ErrWatchlistNotFound
+		case isUniqueViolation(err):
+			err = ErrDuplicateWatchlist
+		default:
+			err = fmt.Errorf("error in updating watchlist; %v", err)
+		}
+		return
+	}
+
+	if _, err = tx.Exec(ctx, DeleteWatchlistMatchesQuery, watchlist.ID); err != nil {
+		err = fmt.Errorf("error in deleting watchlist matches; %v", err)
+		return
+	}
+
+	tag, err := tx.Exec(ctx, BackfillWatchlistQuery, watchlist.ID, backfillFrom.UTC())
+	if err != nil {
+		err = fmt.Errorf("error in matching watchlist; %v", err)
+		return
+	}
+
+	watchlist.Matches = int(tag.RowsAffected())
+	watchlist.Unread = watchlist.Matches
+
+	return nil
+}
+
+func (w WatchlistsModel) DeleteWatchlist(id int, redditUID string) error {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := DeleteWatchlistQuery
+
+	tag, err := w.DB.Exec(ctx, query, id, redditUID)
+	if err != nil {
+		return fmt.Errorf("error in deleting watchlist; %v", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWatchlistNotFound
+	}
+
+	return nil
+}
+
+// MatchPosts matches every watchlist against the posts and returns how many
+// new matches there were.
+func (w WatchlistsModel) MatchPosts(ids []string) (int64, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := MatchWatchlistsQuery
+
+	tag, err := w.DB.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, fmt.Errorf("error in matching watchlists; %v", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetFeed lists the posts that matched any of the user's watchlists, or only
+// watchlistID when it isn't 0, newest match first.
+func (w WatchlistsModel) GetFeed(redditUID string, watchlistID int, unreadOnly bool, filters Filters) ([]WatchlistPost, Metadata, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := WatchlistFeedQuery
+
+	rows, err := w.DB.Query(ctx, query, redditUID, watchlistID, unreadOnly, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error in getting watchlist feed; %v", err)
+	}
+	defer rows.Close()
+
+	posts := []WatchlistPost{}
+	totalRecords := 0
+	for rows.Next() {
+		var post WatchlistPost
+		err = rows.Scan(&totalRecords, &post.ID, &post.Title, &post.Author, &post.URL, &post.Subreddit, &post.Upvotes, &post.NumComments, &post.CreatedUTC, &post.Keywords, &post.MatchedAt, &post.Read)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error in scanning watchlist feed; %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return posts, metadata, nil
+}
+
+// GetUnreadCount returns how many matched posts the user hasn't read.
+func (w WatchlistsModel) GetUnreadCount(redditUID string) (int, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := UnreadMatchesQuery
+
+	var unread int
+	if err := w.DB.QueryRow(ctx, query, redditUID).Scan(&unread); err != nil {
+		return 0, fmt.Errorf("error in counting unread matches; %v", err)
+	}
+
+	return unread, nil
+}
+
+// MarkRead marks the user's matches of the posts as read, or all of them
+// when no posts are given.
+func (w WatchlistsModel) MarkRead(redditUID string, postIDs []string) (int64, error) {
+	ctx, cancel := Handlectx()
+	defer cancel()
+
+	query := MarkMatchesReadQuery
+
+	tag, err := w.DB.Exec(ctx, query, redditUID, postIDs)
+	if err != nil {
+		return 0, fmt.Errorf("error in marking matches read; %v", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,94 @@
+// Package logodds finds the words that set one body of text apart from
+// another with the weighted log-odds ratio and an informative Dirichlet prior
+// (Monroe, Colaresi and Quinn, "Fightin' Words", 2008). Rare words get pulled
+// towards the prior, so a word said twice in one sub and never in the other
+// doesn't beat one the sub says every day.
+package logodds
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultPrior is how many words the prior is worth. The prior is the two
+// counts pooled, scaled to this size.
+const DefaultPrior = 1000
+
+// Score is how much more a word belongs to the first counts than the second.
+// Z is the log-odds ratio over its standard deviation, positive for words of
+// the first counts and negative for the second's.
+type Score struct {
+	Term           string  `json:"term"`
+	Z              float64 `json:"z"`
+	Delta          float64 `json:"delta"`
+	Count          int     `json:"count"`
+	OtherCount     int     `json:"other_count"`
+	Frequency      float64 `json:"frequency"`
+	OtherFrequency float64 `json:"other_frequency"`
+}
+
+// Compare scores every word of a and b said at least minCount times across
+// both, most distinctive of a first and most distinctive of b last. prior is
+// the size of the prior in words, DefaultPrior when it is not positive.
+func Compare(a, b map[string]int, prior float64, minCount int) []Score {
+	if prior <= 0 {
+		prior = DefaultPrior
+	}
+
+	var na, nb float64
+	for _, c := range a {
+		na += float64(c)
+	}
+	for _, c := range b {
+		nb += float64(c)
+	}
+
+	total := na + nb
+	if na == 0 || nb == 0 {
+		return []Score{}
+	}
+
+	terms := make(map[string]bool, len(a)+len(b))
+	for t := range a {
+		terms[t] = true
+	}
+	for t := range b {
+		terms[t] = true
+	}
+
+	scores := make([]Score, 0, len(terms))
+	for term := range terms {
+		ya, yb := float64(a[term]), float64(b[term])
+		if ya+yb < float64(minCount) {
+			continue
+		}
+
+		alpha := prior * (ya + yb) / total
+
+		delta := math.Log((ya+alpha)/(na+prior-ya-alpha)) - math.Log((yb+alpha)/(nb+prior-yb-alpha))
+		variance := 1/(ya+alpha) + 1/(yb+alpha)
+
+		scores = append(scores, Score{
+			Term:           term,
+			Z:              round(delta / math.Sqrt(variance)),
+			Delta:          round(delta),
+			Count:          a[term],
+			OtherCount:     b[term],
+			Frequency:      math.Round(ya/na*1e6) / 1e6,
+			OtherFrequency: math.Round(yb/nb*1e6) / 1e6,
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Z != scores[j].Z {
+			return scores[i].Z > scores[j].Z
+		}
+		return scores[i].Term < scores[j].Term
+	})
+
+	return scores
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
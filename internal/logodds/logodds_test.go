@@ -0,0 +1,135 @@
+package logodds
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func zByTerm(scores []Score) map[string]float64 {
+	z := make(map[string]float64, len(scores))
+	for _, s := range scores {
+		z[s.Term] = s.Z
+	}
+	return z
+}
+
+func TestCompareEmpty(t *testing.T) {
+	counts := map[string]int{"leo": 10}
+
+	tests := []struct {
+		name string
+		a, b map[string]int
+	}{
+		{"both empty", nil, nil},
+		{"first empty", nil, counts},
+		{"second empty", counts, map[string]int{}},
+	}
+
+	for _, tt := range tests {
+		got := Compare(tt.a, tt.b, DefaultPrior, 0)
+		if got == nil || len(got) != 0 {
+			t.Errorf("%s: Compare() = %#v, want an empty slice", tt.name, got)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	kollywood := map[string]int{"thalapathy": 120, "anirudh": 80, "movie": 500, "trailer": 100, "vadivelu": 2}
+	// same totals, so the shared words are as frequent in both
+	bollywood := map[string]int{"srk": 150, "karan": 50, "movie": 500, "trailer": 100, "nepotism": 2}
+
+	scores := Compare(kollywood, bollywood, DefaultPrior, 0)
+	z := zByTerm(scores)
+
+	if len(scores) != 8 {
+		t.Fatalf("Compare() scored %d terms, want 8", len(scores))
+	}
+
+	if first := scores[0].Term; first != "thalapathy" {
+		t.Errorf("most distinctive of the first = %s, want thalapathy", first)
+	}
+	if last := scores[len(scores)-1].Term; last != "srk" {
+		t.Errorf("most distinctive of the second = %s, want srk", last)
+	}
+
+	for _, term := range []string{"movie", "trailer"} {
+		if math.Abs(z[term]) > 0.5 {
+			t.Errorf("z of shared term %s = %v, want near 0", term, z[term])
+		}
+	}
+
+	// the prior pulls a word said twice towards nothing
+	if z["vadivelu"] >= z["anirudh"] {
+		t.Errorf("z of rare vadivelu = %v, want below anirudh's %v", z["vadivelu"], z["anirudh"])
+	}
+
+	for i := 1; i < len(scores); i++ {
+		if scores[i].Z > scores[i-1].Z {
+			t.Fatalf("scores aren't sorted by z: %v after %v", scores[i], scores[i-1])
+		}
+	}
+
+	for _, s := range scores {
+		if s.Term == "srk" {
+			want := Score{Term: "srk", Z: s.Z, Delta: s.Delta, Count: 0, OtherCount: 150, Frequency: 0, OtherFrequency: 0.187032}
+			if !reflect.DeepEqual(s, want) {
+				t.Errorf("srk = %+v, want %+v", s, want)
+			}
+		}
+	}
+}
+
+func TestCompareIsSymmetric(t *testing.T) {
+	a := map[string]int{"jailer": 40, "rajini": 30, "movie": 100}
+	b := map[string]int{"jawan": 50, "srk": 20, "movie": 120}
+
+	forward := zByTerm(Compare(a, b, DefaultPrior, 0))
+	backward := zByTerm(Compare(b, a, DefaultPrior, 0))
+
+	for term, z := range forward {
+		if math.Abs(z+backward[term]) > 0.002 {
+			t.Errorf("z of %s = %v one way and %v the other", term, z, backward[term])
+		}
+	}
+}
+
+func TestCompareMinCount(t *testing.T) {
+	a := map[string]int{"leo": 5, "rare": 1}
+	b := map[string]int{"jawan": 5, "rare": 1, "once": 1}
+
+	tests := []struct {
+		minCount int
+		want     []string
+	}{
+		{0, []string{"jawan", "leo", "once", "rare"}},
+		{2, []string{"jawan", "leo", "rare"}},
+		{3, []string{"jawan", "leo"}},
+		{6, nil},
+	}
+
+	for _, tt := range tests {
+		z := zByTerm(Compare(a, b, DefaultPrior, tt.minCount))
+		if len(z) != len(tt.want) {
+			t.Errorf("minCount %d kept %v, want %v", tt.minCount, z, tt.want)
+			continue
+		}
+		for _, term := range tt.want {
+			if _, ok := z[term]; !ok {
+				t.Errorf("minCount %d dropped %s", tt.minCount, term)
+			}
+		}
+	}
+}
+
+func TestCompareDefaultPrior(t *testing.T) {
+	a := map[string]int{"leo": 30, "movie": 50}
+	b := map[string]int{"jawan": 20, "movie": 60}
+
+	want := Compare(a, b, DefaultPrior, 0)
+	for _, prior := range []float64{0, -1} {
+		if got := Compare(a, b, prior, 0); !reflect.DeepEqual(got, want) {
+			t.Errorf("Compare() with prior %v = %v, want the default prior's %v", prior, got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package minhash estimates how much two short texts overlap, and buckets
+// them with locality sensitive hashing so near duplicates can be found
+// without comparing every pair.
+package minhash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// mersenne61 is the prime the permutations work modulo. Every hash is below
+// it, so signatures fit in a signed 64 bit column.
+const mersenne61 = 1<<61 - 1
+
+// Hasher signs texts with a fixed set of permutations. Signatures are only
+// comparable between hashers built with the same options.
+type Hasher struct {
+	shingle int
+	bands   int
+	rows    int
+	a, b    []uint64
+}
+
+type Options struct {
+	// Shingle is the length in characters of the overlapping pieces a text is
+	// cut into. Titles are short, so characters work better than words.
+	Shingle int
+	// Bands times Rows is the length of a signature. Two texts become
+	// candidates when all rows of any band agree, which happens at a
+	// similarity of about (1/Bands)^(1/Rows).
+	Bands int
+	Rows  int
+	Seed  int64
+}
+
+var DefaultOptions = Options{
+	Shingle: 5,
+	Bands:   16,
+	Rows:    4,
+	Seed:    42,
+}
+
+func New(opts Options) *Hasher {
+	n := opts.Bands * opts.Rows
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	h := &Hasher{
+		shingle: opts.Shingle,
+		bands:   opts.Bands,
+		rows:    opts.Rows,
+		a:       make([]uint64, n),
+		b:       make([]uint64, n),
+	}
+
+	for i := 0; i < n; i++ {
+		h.a[i] = uint64(rng.Int63n(mersenne61-1)) + 1
+		h.b[i] = uint64(rng.Int63n(mersenne61))
+	}
+
+	return h
+}
+
+// normalize keeps the letters and digits of the text, lowercased, with single
+// spaces between words, so punctuation and casing don't hide a repost. Marks
+// are kept too, Tamil and Telugu write their vowel signs with them.
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	}), " ")
+}
+
+func (h *Hasher) shingles(text string) map[uint64]struct{} {
+	runes := []rune(normalize(text))
+	if len(runes) == 0 {
+		return nil
+	}
+
+	set := make(map[uint64]struct{})
+	if len(runes) <= h.shingle {
+		set[hashString(string(runes))] = struct{}{}
+		return set
+	}
+
+	for i := 0; i+h.shingle <= len(runes); i++ {
+		set[hashString(string(runes[i:i+h.shingle]))] = struct{}{}
+	}
+
+	return set
+}
+
+func hashString(s string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(s))
+	return f.Sum64() % mersenne61
+}
+
+// mulmod is a*b mod 2^61-1 without overflowing.
+func mulmod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// 2^64 = 8 mod 2^61-1
+	r := (lo & mersenne61) + (lo >> 61) + (hi << 3)
+	for r >= mersenne61 {
+		r -= mersenne61
+	}
+	return r
+}
+
+// Signature is the minimum of every permutation over the shingles of the
+// text, or nil for a text with nothing to compare.
+func (h *Hasher) Signature(text string) []int64 {
+	set := h.shingles(text)
+	if len(set) == 0 {
+		return nil
+	}
+
+	sig := make([]int64, len(h.a))
+	for i := range sig {
+		min := uint64(mersenne61)
+		for x := range set {
+			v := mulmod(h.a[i], x) + h.b[i]
+			if v >= mersenne61 {
+				v -= mersenne61
+			}
+			if v < min {
+				min = v
+			}
+		}
+		sig[i] = int64(min)
+	}
+
+	return sig
+}
+
+// Bands hashes each band of a signature into a bucket key. The band number is
+// part of the key, only the same band of two signatures should collide.
+func (h *Hasher) Bands(sig []int64) []string {
+	if len(sig) != h.bands*h.rows {
+		return nil
+	}
+
+	keys := make([]string, h.bands)
+	buf := make([]byte, 8)
+	for band := 0; band < h.bands; band++ {
+		f := fnv.New64a()
+		for _, v := range sig[band*h.rows : (band+1)*h.rows] {
+			binary.LittleEndian.PutUint64(buf, uint64(v))
+			f.Write(buf)
+		}
+		keys[band] = fmt.Sprintf("%d:%x", band, f.Sum64())
+	}
+
+	return keys
+}
+
+// Similarity estimates the Jaccard similarity of the texts behind two
+// signatures as the share of permutations they agree on.
+func Similarity(a, b []int64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+
+	return float64(same) / float64(len(a))
+}
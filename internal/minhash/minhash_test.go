@@ -0,0 +1,148 @@
+package minhash
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Leo Trailer", "leo trailer"},
+		{"  LEO -- Official   Trailer!! ", "leo official trailer"},
+		{"Jawan: Day 3 (Hindi)", "jawan day 3 hindi"},
+		{"விக்ரம் ட்ரைலர்", "விக்ரம் ட்ரைலர்"},
+		{"?!...", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalize(tt.text); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMulmod(t *testing.T) {
+	tests := []struct{ a, b uint64 }{
+		{0, 12345},
+		{1, mersenne61 - 1},
+		{mersenne61 - 1, mersenne61 - 1},
+		{1 << 60, 1 << 60},
+		{123456789123456789, 987654321987654321 % mersenne61},
+	}
+
+	p := new(big.Int).SetUint64(mersenne61)
+	for _, tt := range tests {
+		want := new(big.Int).Mul(new(big.Int).SetUint64(tt.a), new(big.Int).SetUint64(tt.b))
+		want.Mod(want, p)
+		if got := mulmod(tt.a, tt.b); got != want.Uint64() {
+			t.Errorf("mulmod(%d, %d) = %d, want %d", tt.a, tt.b, got, want.Uint64())
+		}
+	}
+}
+
+func TestSignatureSimilarity(t *testing.T) {
+	h := New(DefaultOptions)
+
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max float64
+	}{
+		{"same text", "Leo official trailer", "Leo official trailer", 1, 1},
+		{"case and punctuation", "Leo - Official Trailer!", "leo official trailer", 1, 1},
+		{"near duplicate", "Leo official trailer out now", "Leo official trailer is out now", 0.5, 1},
+		{"unrelated", "Leo official trailer out now", "Jawan box office collection day 3", 0, 0.2},
+		{"short texts", "Leo", "Leo", 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(h.Signature(tt.a), h.Signature(tt.b))
+			if got < tt.min || got > tt.max {
+				t.Errorf("Similarity() = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestSignatureOfNothing(t *testing.T) {
+	h := New(DefaultOptions)
+
+	for _, text := range []string{"", "   ", "!!!"} {
+		if sig := h.Signature(text); sig != nil {
+			t.Errorf("Signature(%q) = %v, want nil", text, sig)
+		}
+	}
+}
+
+func TestSignatureIsStable(t *testing.T) {
+	a := New(DefaultOptions).Signature("Leo official trailer")
+	b := New(DefaultOptions).Signature("Leo official trailer")
+
+	if Similarity(a, b) != 1 {
+		t.Error("signatures of two hashers with the same options differ")
+	}
+
+	for _, v := range a {
+		if v < 0 || v >= mersenne61 {
+			t.Fatalf("signature value %d out of range", v)
+		}
+	}
+}
+
+func TestBands(t *testing.T) {
+	h := New(DefaultOptions)
+	sig := h.Signature("Leo official trailer")
+
+	keys := h.Bands(sig)
+	if len(keys) != DefaultOptions.Bands {
+		t.Fatalf("Bands() returned %d keys, want %d", len(keys), DefaultOptions.Bands)
+	}
+
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		if !strings.HasPrefix(key, strconv.Itoa(i)+":") {
+			t.Errorf("key %q of band %d lacks its band number", key, i)
+		}
+		if seen[key] {
+			t.Errorf("key %q repeats", key)
+		}
+		seen[key] = true
+	}
+
+	other := h.Bands(h.Signature("Leo - official trailer"))
+	for i := range keys {
+		if keys[i] != other[i] {
+			t.Errorf("band %d differs for the same normalized text", i)
+		}
+	}
+
+	if got := h.Bands(sig[1:]); got != nil {
+		t.Errorf("Bands() of a short signature = %v, want nil", got)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []int64
+		want float64
+	}{
+		{"equal", []int64{1, 2, 3, 4}, []int64{1, 2, 3, 4}, 1},
+		{"half", []int64{1, 2, 3, 4}, []int64{1, 2, 5, 6}, 0.5},
+		{"none", []int64{1, 2}, []int64{3, 4}, 0},
+		{"lengths differ", []int64{1, 2}, []int64{1, 2, 3}, 0},
+		{"empty", nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Similarity() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package ratings pulls the scores reviewers give out of their posts, like
+// "3.5/5", "7 out of 10" or "4 stars", on a scale of 10.
+package ratings
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SourceTitle = "title"
+	SourceBody  = "body"
+)
+
+// Scale is what every rating is brought to.
+const Scale = 10
+
+// overrated is how far past full marks a rating can go and still count as
+// full marks, for the 11/10s fans hand out.
+const overrated = 1.2
+
+type Rating struct {
+	// Value is the rating out of Scale.
+	Value float64 `json:"value"`
+	// Raw is the rating as written, like "3.5/5".
+	Raw    string `json:"raw"`
+	Source string `json:"source"`
+}
+
+var (
+	fraction = regexp.MustCompile(`(?i)(\d{1,3}(?:\.\d{1,2})?)\s*(?:/|out\s+of)\s*(100|10|5)\b`)
+	stars    = regexp.MustCompile(`(?i)(\d(?:\.\d{1,2})?)\s*(?:stars?|★)`)
+)
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// standalone tells whether the match at text[start:end] is a score rather
+// than a piece of a date like 12/10/2023 or a longer number.
+func standalone(text string, start, end int) bool {
+	if start > 0 {
+		prev := text[start-1]
+		if isDigit(prev) || prev == '/' || prev == '.' {
+			return false
+		}
+	}
+
+	if end < len(text) {
+		next := text[end]
+		if next == '/' || isDigit(next) {
+			return false
+		}
+		if next == '.' && end+1 < len(text) && isDigit(text[end+1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Find returns the ratings in the text in the order they are written.
+func Find(text, source string) []Rating {
+	type found struct {
+		start, end int
+		rating     Rating
+	}
+
+	var all []found
+	add := func(start, end int, value, outOf float64) {
+		if value > outOf*overrated || !standalone(text, start, end) {
+			return
+		}
+		value = min(value, outOf)
+		// "4/5 stars" is one rating, not a 4/5 and a 5 stars
+		for _, f := range all {
+			if start < f.end && f.start < end {
+				return
+			}
+		}
+		all = append(all, found{start, end, Rating{
+			Value:  math.Round(value/outOf*Scale*100) / 100,
+			Raw:    strings.Join(strings.Fields(text[start:end]), " "),
+			Source: source,
+		}})
+	}
+
+	for _, m := range fraction.FindAllStringSubmatchIndex(text, -1) {
+		value, err := strconv.ParseFloat(text[m[2]:m[3]], 64)
+		if err != nil {
+			continue
+		}
+		outOf, _ := strconv.ParseFloat(text[m[4]:m[5]], 64)
+		add(m[0], m[1], value, outOf)
+	}
+
+	for _, m := range stars.FindAllStringSubmatchIndex(text, -1) {
+		value, err := strconv.ParseFloat(text[m[2]:m[3]], 64)
+		if err != nil {
+			continue
+		}
+		add(m[0], m[1], value, 5)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].start < all[j].start
+	})
+
+	ratings := make([]Rating, len(all))
+	for i, f := range all {
+		ratings[i] = f.rating
+	}
+
+	return ratings
+}
+
+// Extract picks the rating of a review. A rating in the title is the
+// reviewer's headline, so the first one there wins. Otherwise it is the last
+// one in the body, since reviews tend to end on the verdict after rating
+// the songs or the acting on the way.
+func Extract(title, body string) (Rating, bool) {
+	if found := Find(title, SourceTitle); len(found) > 0 {
+		return found[0], true
+	}
+
+	if found := Find(body, SourceBody); len(found) > 0 {
+		return found[len(found)-1], true
+	}
+
+	return Rating{}, false
+}
@@ -0,0 +1,92 @@
+package ratings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Rating
+	}{
+		{"out of five", "Leo review: 3.5/5", []Rating{{Value: 7, Raw: "3.5/5"}}},
+		{"out of ten in words", "I'd give it 7 out of 10", []Rating{{Value: 7, Raw: "7 out of 10"}}},
+		{"out of hundred", "85/100 for the bgm alone", []Rating{{Value: 8.5, Raw: "85/100"}}},
+		{"spaces around the slash", "Rating - 4 / 5", []Rating{{Value: 8, Raw: "4 / 5"}}},
+		{"two decimals", "2.75/5", []Rating{{Value: 5.5, Raw: "2.75/5"}}},
+		{"stars", "Solid 4 stars", []Rating{{Value: 8, Raw: "4 stars"}}},
+		{"star sign", "3.5★", []Rating{{Value: 7, Raw: "3.5★"}}},
+		{"fraction and stars are one rating", "4/5 stars", []Rating{{Value: 8, Raw: "4/5"}}},
+		{"fan overrating is capped", "11/10 would watch again", []Rating{{Value: 10, Raw: "11/10"}}},
+		{"zero", "0/10", []Rating{{Value: 0, Raw: "0/10"}}},
+		{"in the order written", "Songs 4/5, overall 7/10", []Rating{{Value: 8, Raw: "4/5"}, {Value: 7, Raw: "7/10"}}},
+		{"too far past full marks", "15/10", nil},
+		{"date", "Releasing on 12/10/2023", nil},
+		{"date with dots", "Trailer at 5.10/10.30", nil},
+		{"share of something", "made on 1/5th of the budget", nil},
+		{"longer number", "1234/10", nil},
+		{"no rating", "What did you think of Jailer?", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Find(tt.text, SourceBody)
+			for i := range tt.want {
+				tt.want[i].Source = SourceBody
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		body   string
+		want   Rating
+		wantOK bool
+	}{
+		{
+			name:   "title wins over the body",
+			title:  "Jailer review - 4/5",
+			body:   "Songs 3/5, overall 3.5/5",
+			want:   Rating{Value: 8, Raw: "4/5", Source: SourceTitle},
+			wantOK: true,
+		},
+		{
+			name:   "first in the title",
+			title:  "Leo 3/5, Jawan 4/5",
+			want:   Rating{Value: 6, Raw: "3/5", Source: SourceTitle},
+			wantOK: true,
+		},
+		{
+			name:   "last in the body",
+			title:  "Jailer review",
+			body:   "Songs 3/5, acting 4/5. Overall 7 out of 10",
+			want:   Rating{Value: 7, Raw: "7 out of 10", Source: SourceBody},
+			wantOK: true,
+		},
+		{
+			name:  "no rating",
+			title: "Jailer review",
+			body:  "Watched it on 10/08/2023, loved it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Extract(tt.title, tt.body)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Extract() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package sentiment scores short texts with a word list tuned to how movie
+// fans write on reddit.
+package sentiment
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// lexicon is how positive or negative a word is, from -3 to 3.
+var lexicon = map[string]float64{
+	// general
+	"good": 1.5, "great": 2.5, "best": 2.5, "better": 1.5, "amazing": 3, "awesome": 3,
+	"excellent": 3, "fantastic": 3, "brilliant": 3, "superb": 3, "outstanding": 3,
+	"wonderful": 2.5, "beautiful": 2, "love": 2.5, "loved": 2.5, "loving": 2, "like": 0.8,
+	"liked": 1.2, "enjoy": 1.5, "enjoyed": 1.8, "fun": 1.5, "happy": 2, "glad": 1.5,
+	"nice": 1.5, "decent": 1, "solid": 1.2, "fine": 0.8, "perfect": 3, "impressive": 2.2,
+	"impressed": 2, "favourite": 2, "favorite": 2, "win": 2, "wins": 2, "won": 1.8,
+	"success": 2, "successful": 2, "proud": 2, "respect": 1.5, "worth": 1.5,
+	"bad": -2, "worse": -2.2, "worst": -3, "awful": -3, "terrible": -3, "horrible": -3,
+	"poor": -1.8, "hate": -2.7, "hated": -2.7, "boring": -2.2, "bored": -1.8, "dull": -1.8,
+	"waste": -2.5, "wasted": -2.2, "weak": -1.5, "mediocre": -1.5, "meh": -1, "sad": -1.5,
+	"angry": -2, "annoying": -2, "disappointed": -2.2, "disappointing": -2.2,
+	"disappointment": -2.2, "fail": -2, "failed": -2, "failure": -2.2, "lost": -1,
+	"loses": -1.5, "mess": -2, "stupid": -2.2, "pathetic": -2.8, "shame": -2,
+	"overrated": -1.8, "underwhelming": -2, "ruined": -2.5, "garbage": -3, "trash": -2.8,
+
+	// movies
+	"masterpiece": 3, "classic": 2, "gem": 2.5, "blockbuster": 2.5, "hit": 1.8,
+	"superhit": 2.8, "allrounder": 2.2, "record": 1.2, "records": 1.2, "goosebumps": 2.5,
+	"mass": 1.8, "celebration": 2, "fire": 1.8, "banger": 2.5, "chartbuster": 2.5,
+	"engaging": 2, "gripping": 2.2, "entertaining": 2, "entertainer": 2, "stunning": 2.8,
+	"hype": 1.5, "hyped": 1.5, "excited": 2, "exciting": 2, "cant wait": 2, "waiting": 0.5,
+	"recommended": 2, "recommend": 1.8, "rewatch": 1.5, "housefull": 2.2, "sold": 0.8,
+	"flop": -2.5, "disaster": -3, "dud": -2.5, "debacle": -3, "bore": -2, "lag": -1.2,
+	"lagging": -1.5, "dragged": -1.8, "draggy": -1.8, "cringe": -2.2, "cringy": -2.2,
+	"outdated": -1.5, "predictable": -1.2, "loud": -0.8, "copied": -1.5, "remake": -0.5,
+	"propaganda": -2, "boycott": -2.5, "troll": -1.2, "trolled": -1.5, "leaked": -1.5,
+	"postponed": -1.2, "delayed": -1.2, "fake": -1.8, "paid": -1,
+}
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "nothing": true, "neither": true, "nor": true,
+	"hardly": true, "isnt": true, "wasnt": true, "arent": true, "werent": true,
+	"dont": true, "doesnt": true, "didnt": true, "cant": true, "cannot": true,
+	"wont": true, "wouldnt": true, "shouldnt": true, "aint": true, "without": true,
+}
+
+var boosters = map[string]float64{
+	"very": 0.3, "so": 0.25, "really": 0.3, "extremely": 0.5, "super": 0.4, "too": 0.2,
+	"absolutely": 0.4, "totally": 0.3, "truly": 0.3, "highly": 0.3, "most": 0.2,
+	"slightly": -0.3, "somewhat": -0.3, "bit": -0.3, "kinda": -0.3,
+}
+
+const (
+	// negationScope is how many words after a negation it flips.
+	negationScope = 3
+	// negationDamp is how much of the flipped score is kept, since "not good"
+	// is less negative than "bad".
+	negationDamp = 0.75
+	// alpha brings the summed scores into (-1, 1); the higher, the more it
+	// takes to get near either end.
+	alpha = 15
+)
+
+func tokenize(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "'", "")
+	text = strings.ReplaceAll(text, "’", "")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Score is how positive the text reads, from -1 to 1, with 0 for a text that
+// has no words in the list.
+func Score(text string) float64 {
+	tokens := tokenize(text)
+
+	var sum float64
+	negated := 0
+	boost := 0.0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		// phrases come first, or "cant wait" would read as a negation
+		valence, ok := 0.0, false
+		if i+1 < len(tokens) {
+			if valence, ok = lexicon[token+" "+tokens[i+1]]; ok {
+				i++
+			}
+		}
+
+		if !ok {
+			if negations[token] {
+				negated = negationScope
+				continue
+			}
+
+			if b, isBooster := boosters[token]; isBooster {
+				boost += b
+				continue
+			}
+
+			valence, ok = lexicon[token]
+		}
+
+		if ok {
+			valence *= 1 + boost
+			if negated > 0 {
+				valence *= -negationDamp
+			}
+			sum += valence
+		}
+
+		boost = 0
+		if negated > 0 {
+			negated--
+		}
+	}
+
+	if sum == 0 {
+		return 0
+	}
+
+	return sum / math.Sqrt(sum*sum+alpha)
+}
@@ -0,0 +1,71 @@
+package sentiment
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestScoreSign(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Leo releases on Friday", 0},
+		{"Absolute masterpiece, goosebumps in the interval", 1},
+		{"Worst movie of the year, total disaster", -1},
+		{"not good", -1},
+		{"not bad at all", 1},
+		{"I didn't like it", -1},
+		{"I didn’t like it", -1},
+		{"Can't wait for the trailer", 1},
+		{"cant wait", 1},
+		{"Not that I care, the songs are good", 1},
+		{"FLOP", -1},
+	}
+
+	for _, tt := range tests {
+		got := Score(tt.text)
+		sign := 0
+		if got > 0 {
+			sign = 1
+		} else if got < 0 {
+			sign = -1
+		}
+		if sign != tt.want {
+			t.Errorf("Score(%q) = %v, want sign %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestScoreOrdering(t *testing.T) {
+	tests := []struct {
+		name           string
+		weaker, strong string
+	}{
+		{"booster", "good", "very good"},
+		{"stronger word", "good", "amazing"},
+		{"more words", "good", "good songs and great bgm"},
+		{"negation is damped", "not good", "bad"},
+		{"softener", "slightly boring", "boring"},
+	}
+
+	for _, tt := range tests {
+		weaker, stronger := Score(tt.weaker), Score(tt.strong)
+		if math.Abs(weaker) >= math.Abs(stronger) {
+			t.Errorf("%s: |Score(%q)| = %v, want below |Score(%q)| = %v", tt.name, tt.weaker, weaker, tt.strong, stronger)
+		}
+	}
+}
+
+func TestScoreIsBounded(t *testing.T) {
+	for _, text := range []string{
+		strings.Repeat("amazing masterpiece ", 200),
+		strings.Repeat("worst disaster ", 200),
+	} {
+		if got := Score(text); got <= -1 || got >= 1 {
+			t.Errorf("Score() = %v, want within (-1, 1)", got)
+		}
+	}
+}
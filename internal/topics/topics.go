@@ -0,0 +1,328 @@
+// Package topics groups documents into topics with spherical k-means over
+// their TF-IDF vectors.
+package topics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Document is a post by its term counts.
+type Document struct {
+	ID    string
+	Terms map[string]int
+}
+
+type TermWeight struct {
+	Term   string  `json:"term"`
+	Weight float64 `json:"weight"`
+}
+
+type Member struct {
+	ID         string
+	Similarity float64
+}
+
+// Topic is a cluster of documents. Terms are the heaviest terms of its
+// centroid and Members are its documents, closest to the centroid first.
+type Topic struct {
+	Terms   []TermWeight
+	Members []Member
+}
+
+type Options struct {
+	// MaxTopics caps k, and PostsPerTopic sets it below the cap, so a quiet
+	// month isn't split into topics of a post or two.
+	MaxTopics     int
+	PostsPerTopic int
+	// MinDocFreq drops terms too rare to tie posts together, MaxDocShare
+	// drops terms in so many posts they say nothing about any of them.
+	MinDocFreq  int
+	MaxDocShare float64
+	TopTerms    int
+	Iterations  int
+	// Restarts runs k-means from that many seedings and keeps the tightest
+	// result, since a seeding with two centroids in the same topic never
+	// recovers.
+	Restarts int
+	Seed     int64
+}
+
+var DefaultOptions = Options{
+	MaxTopics:     8,
+	PostsPerTopic: 15,
+	MinDocFreq:    2,
+	MaxDocShare:   0.5,
+	TopTerms:      10,
+	Iterations:    30,
+	Restarts:      5,
+	Seed:          1,
+}
+
+type vector map[int]float64
+
+func (v vector) dot(dense []float64) float64 {
+	var sum float64
+	for i, w := range v {
+		sum += w * dense[i]
+	}
+	return sum
+}
+
+func normalize(dense []float64) {
+	var norm float64
+	for _, w := range dense {
+		norm += w * w
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range dense {
+		dense[i] /= norm
+	}
+}
+
+// vectorize builds the unit TF-IDF vector of every document over the
+// vocabulary left after the document frequency cuts. Documents left with
+// no terms are dropped.
+func vectorize(docs []Document, opts Options) ([]string, []vector, []Document) {
+	df := make(map[string]int)
+	for _, doc := range docs {
+		for term := range doc.Terms {
+			df[term]++
+		}
+	}
+
+	maxDF := int(math.Floor(opts.MaxDocShare * float64(len(docs))))
+	var vocabulary []string
+	for term, n := range df {
+		if n >= opts.MinDocFreq && n <= maxDF {
+			vocabulary = append(vocabulary, term)
+		}
+	}
+	sort.Strings(vocabulary)
+
+	index := make(map[string]int, len(vocabulary))
+	idf := make([]float64, len(vocabulary))
+	for i, term := range vocabulary {
+		index[term] = i
+		idf[i] = math.Log(float64(len(docs)) / float64(df[term]))
+	}
+
+	var vectors []vector
+	var kept []Document
+	for _, doc := range docs {
+		v := make(vector)
+		var norm float64
+		for term, count := range doc.Terms {
+			i, ok := index[term]
+			if !ok || count <= 0 {
+				continue
+			}
+			w := (1 + math.Log(float64(count))) * idf[i]
+			v[i] = w
+			norm += w * w
+		}
+
+		if norm == 0 {
+			continue
+		}
+
+		norm = math.Sqrt(norm)
+		for i := range v {
+			v[i] /= norm
+		}
+
+		vectors = append(vectors, v)
+		kept = append(kept, doc)
+	}
+
+	return vocabulary, vectors, kept
+}
+
+// seed picks the first centroids k-means++ style: each next one is a
+// document drawn with odds growing with its distance from the picked ones.
+func seed(vectors []vector, k, dims int, rng *rand.Rand) [][]float64 {
+	centroids := make([][]float64, 0, k)
+	distances := make([]float64, len(vectors))
+	for i := range distances {
+		distances[i] = math.Inf(1)
+	}
+
+	next := rng.Intn(len(vectors))
+	for len(centroids) < k {
+		centroid := make([]float64, dims)
+		for i, w := range vectors[next] {
+			centroid[i] = w
+		}
+		centroids = append(centroids, centroid)
+
+		var total float64
+		for i, v := range vectors {
+			// both are unit vectors, so 1 - cosine is half the squared distance
+			d := 1 - v.dot(centroid)
+			if d < distances[i] {
+				distances[i] = d
+			}
+			total += distances[i]
+		}
+
+		if total <= 0 {
+			break
+		}
+
+		r := rng.Float64() * total
+		for i, d := range distances {
+			r -= d
+			if r <= 0 {
+				next = i
+				break
+			}
+		}
+	}
+
+	return centroids
+}
+
+// kmeans runs spherical k-means from one seeding. The objective is the total
+// similarity of the documents to their centroids, higher is tighter.
+func kmeans(vectors []vector, k, dims, iterations int, rng *rand.Rand) ([][]float64, []int, float64) {
+	centroids := seed(vectors, k, dims, rng)
+
+	assignment := make([]int, len(vectors))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		changed := false
+		for i, v := range vectors {
+			best, bestSim := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if sim := v.dot(centroid); sim > bestSim {
+					best, bestSim = c, sim
+				}
+			}
+			if assignment[i] != best {
+				assignment[i] = best
+				changed = true
+			}
+		}
+
+		if !changed {
+			break
+		}
+
+		for c := range centroids {
+			centroids[c] = make([]float64, dims)
+		}
+		for i, v := range vectors {
+			for t, w := range v {
+				centroids[assignment[i]][t] += w
+			}
+		}
+		for c := range centroids {
+			normalize(centroids[c])
+		}
+	}
+
+	var objective float64
+	for i, v := range vectors {
+		objective += v.dot(centroids[assignment[i]])
+	}
+
+	return centroids, assignment, objective
+}
+
+// Cluster groups the documents into topics, largest first.
+func Cluster(docs []Document, opts Options) []Topic {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	vocabulary, vectors, docs := vectorize(docs, opts)
+
+	k := min(opts.MaxTopics, len(vectors)/max(opts.PostsPerTopic, 1))
+	if k < 1 || len(vocabulary) == 0 {
+		return nil
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	var centroids [][]float64
+	var assignment []int
+	bestObjective := math.Inf(-1)
+	for restart := 0; restart < max(opts.Restarts, 1); restart++ {
+		c, a, objective := kmeans(vectors, k, len(vocabulary), opts.Iterations, rng)
+		if objective > bestObjective {
+			centroids, assignment, bestObjective = c, a, objective
+		}
+	}
+	k = len(centroids)
+
+	topics := make([]Topic, k)
+	for i, v := range vectors {
+		c := assignment[i]
+		topics[c].Members = append(topics[c].Members, Member{ID: docs[i].ID, Similarity: v.dot(centroids[c])})
+	}
+
+	for c := range topics {
+		sort.Slice(topics[c].Members, func(i, j int) bool {
+			return topics[c].Members[i].Similarity > topics[c].Members[j].Similarity
+		})
+
+		terms := make([]TermWeight, 0, len(vocabulary))
+		for t, w := range centroids[c] {
+			if w > 0 {
+				terms = append(terms, TermWeight{Term: vocabulary[t], Weight: w})
+			}
+		}
+		sort.Slice(terms, func(i, j int) bool {
+			if terms[i].Weight != terms[j].Weight {
+				return terms[i].Weight > terms[j].Weight
+			}
+			return terms[i].Term < terms[j].Term
+		})
+		if len(terms) > opts.TopTerms {
+			terms = terms[:opts.TopTerms]
+		}
+		topics[c].Terms = terms
+	}
+
+	// a centroid nobody was closest to leaves an empty topic behind
+	kept := topics[:0]
+	for _, topic := range topics {
+		if len(topic.Members) > 0 {
+			kept = append(kept, topic)
+		}
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return len(kept[i].Members) > len(kept[j].Members)
+	})
+
+	return kept
+}
+
+// Similarity is the cosine of two topics by their top terms, for following a
+// topic from one month to the next.
+func Similarity(a, b []TermWeight) float64 {
+	weights := make(map[string]float64, len(a))
+	var normA, normB, dot float64
+	for _, t := range a {
+		weights[t.Term] = t.Weight
+		normA += t.Weight * t.Weight
+	}
+	for _, t := range b {
+		dot += weights[t.Term] * t.Weight
+		normB += t.Weight * t.Weight
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / math.Sqrt(normA*normB)
+}
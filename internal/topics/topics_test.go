@@ -0,0 +1,146 @@
+package topics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// groupDocs makes n documents that each use two of the terms, so the terms
+// tie the documents of a group together without any being in all of them.
+func groupDocs(prefix string, terms []string, n int) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{
+			ID: fmt.Sprintf("%s%d", prefix, i),
+			Terms: map[string]int{
+				terms[i%len(terms)]:     1,
+				terms[(i+1)%len(terms)]: 2,
+			},
+		}
+	}
+	return docs
+}
+
+func TestCluster(t *testing.T) {
+	boxOffice := []string{"collection", "gross", "crore"}
+	trailers := []string{"trailer", "teaser", "song"}
+
+	docs := append(groupDocs("bo", boxOffice, 12), groupDocs("tr", trailers, 12)...)
+
+	opts := DefaultOptions
+	opts.PostsPerTopic = 6
+	opts.MaxTopics = 2
+
+	topics := Cluster(docs, opts)
+	if len(topics) != 2 {
+		t.Fatalf("Cluster() returned %d topics, want 2", len(topics))
+	}
+
+	for _, topic := range topics {
+		if len(topic.Members) != 12 {
+			t.Errorf("topic has %d members, want 12", len(topic.Members))
+		}
+
+		prefix := topic.Members[0].ID[:2]
+		for i, m := range topic.Members {
+			if !strings.HasPrefix(m.ID, prefix) {
+				t.Errorf("topic of %s also holds %s", prefix, m.ID)
+			}
+			if i > 0 && m.Similarity > topic.Members[i-1].Similarity {
+				t.Errorf("members aren't sorted by similarity")
+			}
+		}
+
+		want := boxOffice
+		if prefix == "tr" {
+			want = trailers
+		}
+		if len(topic.Terms) != len(want) {
+			t.Errorf("topic of %s has terms %v, want %v", prefix, topic.Terms, want)
+		}
+		for _, term := range topic.Terms {
+			if !strings.Contains(strings.Join(want, " "), term.Term) {
+				t.Errorf("topic of %s has foreign term %s", prefix, term.Term)
+			}
+		}
+	}
+}
+
+func TestClusterIsDeterministic(t *testing.T) {
+	docs := append(groupDocs("bo", []string{"collection", "gross", "crore"}, 20), groupDocs("tr", []string{"trailer", "teaser", "song"}, 20)...)
+
+	opts := DefaultOptions
+	opts.PostsPerTopic = 5
+
+	first := Cluster(docs, opts)
+	second := Cluster(docs, opts)
+
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Error("Cluster() differs between runs with the same seed")
+	}
+}
+
+func TestClusterTooLittleToGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		docs []Document
+		opts Options
+	}{
+		{"no documents", nil, DefaultOptions},
+		{"fewer documents than a topic needs", groupDocs("bo", []string{"collection", "gross", "crore"}, 10), DefaultOptions},
+		{
+			"only rare terms",
+			[]Document{{ID: "a", Terms: map[string]int{"leo": 1}}, {ID: "b", Terms: map[string]int{"jawan": 1}}},
+			Options{MaxTopics: 2, PostsPerTopic: 1, MinDocFreq: 2, MaxDocShare: 0.5, TopTerms: 10, Iterations: 10},
+		},
+		{
+			"only common terms",
+			[]Document{{ID: "a", Terms: map[string]int{"movie": 1}}, {ID: "b", Terms: map[string]int{"movie": 1}}},
+			Options{MaxTopics: 2, PostsPerTopic: 1, MinDocFreq: 1, MaxDocShare: 0.5, TopTerms: 10, Iterations: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Cluster(tt.docs, tt.opts); got != nil {
+				t.Errorf("Cluster() = %v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []TermWeight
+		want float64
+	}{
+		{
+			"same terms",
+			[]TermWeight{{"trailer", 0.8}, {"teaser", 0.6}},
+			[]TermWeight{{"teaser", 0.6}, {"trailer", 0.8}},
+			1,
+		},
+		{
+			"no shared terms",
+			[]TermWeight{{"trailer", 1}},
+			[]TermWeight{{"gross", 1}},
+			0,
+		},
+		{
+			"some shared terms",
+			[]TermWeight{{"trailer", 1}, {"teaser", 1}},
+			[]TermWeight{{"trailer", 1}, {"gross", 1}},
+			0.5,
+		},
+		{"empty", nil, []TermWeight{{"trailer", 1}}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Similarity() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
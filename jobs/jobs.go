@@ -1,10 +1,8 @@
 package jobs
 
 import (
-	"bytes"
-	"encoding/json"
-	"os/exec"
-	"strings"
+	"fmt"
+	"time"
 
 	"github.com/go-co-op/gocron/v2"
 	"github.com/labstack/gommon/log"
@@ -17,45 +15,29 @@ func UpdateWordClouds(h handlers.Handlers, scheduler gocron.Scheduler, atTimes g
 
 		subs := []string{"kollywood", "bollywood", "tollywood", "MalayalamMovies"}
 
+		var failed []string
 		for _, sub := range subs {
-			words, err := h.GetTrendingWordsHandler(sub, "month")
-			if err != nil {
-				log.Error("Error updating word clouds: ", err)
+			if err := h.WriteWordCloudPNG(sub); err != nil {
+				log.Error("Error updating word cloud for ", sub, ": ", err)
+				failed = append(failed, sub)
+				continue
 			}
+			log.Info("Generated wordcloud for ", sub)
+		}
 
-			jsonWords := map[string][]handlers.WordCount{
-				sub: words,
-			}
-
-			jsonBytes, err := json.Marshal(jsonWords)
-			if err != nil {
-				log.Error("Error marshalling json: ", err)
-				return err
-			}
-
-			cmd := exec.Command("wordcloud/py-venv/bin/python", "wordcloud/main.py")
-			cmd.Stdin = strings.NewReader(string(jsonBytes))
-
-			var stdout, stderr bytes.Buffer
-			cmd.Stdout = &stdout
-			cmd.Stderr = &stderr
-
-			err = cmd.Run()
-			if err != nil {
-				log.Error("Error running wordcloud script: ", err)
-				log.Error("Python script stdout: ", stdout.String())
-				log.Error("Python script stderr: ", stderr.String())
-				return err
-			}
-			log.Info("updateWordClouds completed. Stdout: ", stdout.String())
+		if len(failed) > 0 {
+			return fmt.Errorf("word clouds failed for %v", failed)
 		}
 
+		log.Info("updateWordClouds completed")
 		return nil
 	}))
 
 	return job, err
 }
 
+// UpdateRedditPostsJob ingests the day's posts, then looks for activity
+// spikes in them, so the spikes never run on stale posts.
 func UpdateRedditPostsJob(h handlers.Handlers, scheduler gocron.Scheduler, atTimes gocron.AtTimes) (gocron.Job, error) {
 	job, err := scheduler.NewJob(gocron.DailyJob(1, atTimes), gocron.NewTask(func() error {
 		log.Info("Running updateRedditPostsJob")
@@ -64,9 +46,84 @@ func UpdateRedditPostsJob(h handlers.Handlers, scheduler gocron.Scheduler, atTim
 			return err
 		}
 
+		log.Info("Detecting activity spikes")
+
+		if err := h.DetectActivitySpikes(); err != nil {
+			log.Error("Error detecting activity spikes: ", err)
+			return err
+		}
+
 		log.Info("updateRedditPostsJob completed")
 		return nil
 	}))
 
 	return job, err
 }
+
+// WeeklyDigestJob runs on monday morning, once sunday's posts are in.
+func WeeklyDigestJob(h handlers.Handlers, scheduler gocron.Scheduler, atTimes gocron.AtTimes) (gocron.Job, error) {
+	job, err := scheduler.NewJob(gocron.WeeklyJob(1, gocron.NewWeekdays(time.Monday), atTimes), gocron.NewTask(func() error {
+		log.Info("Running weeklyDigestJob")
+
+		if err := h.BuildWeeklyDigests(); err != nil {
+			log.Error("Error building weekly digests: ", err)
+			return err
+		}
+
+		log.Info("weeklyDigestJob completed")
+		return nil
+	}))
+
+	return job, err
+}
+
+// MonthlyTopicsJob runs on the first of the month, once the month before is
+// over.
+func MonthlyTopicsJob(h handlers.Handlers, scheduler gocron.Scheduler, atTimes gocron.AtTimes) (gocron.Job, error) {
+	job, err := scheduler.NewJob(gocron.MonthlyJob(1, gocron.NewDaysOfTheMonth(1), atTimes), gocron.NewTask(func() error {
+		log.Info("Running monthlyTopicsJob")
+
+		if err := h.BuildMonthlyTopics(); err != nil {
+			log.Error("Error building monthly topics: ", err)
+			return err
+		}
+
+		log.Info("monthlyTopicsJob completed")
+		return nil
+	}))
+
+	return job, err
+}
+
+// YearlyRecapJob runs on the first of the month, bringing the recap of the
+// year so far up to date. On new year's day it finishes the year before.
+func YearlyRecapJob(h handlers.Handlers, scheduler gocron.Scheduler, atTimes gocron.AtTimes) (gocron.Job, error) {
+	job, err := scheduler.NewJob(gocron.MonthlyJob(1, gocron.NewDaysOfTheMonth(1), atTimes), gocron.NewTask(func() error {
+		log.Info("Running yearlyRecapJob")
+
+		if err := h.BuildYearlyRecaps(); err != nil {
+			log.Error("Error building yearly recaps: ", err)
+			return err
+		}
+
+		log.Info("yearlyRecapJob completed")
+		return nil
+	}))
+
+	return job, err
+}
+
+// PollTrackingWindowsJob polls the subs with a running tracking window every
+// interval. A poll still running when the next is due makes it wait.
+func PollTrackingWindowsJob(h handlers.Handlers, scheduler gocron.Scheduler, interval time.Duration) (gocron.Job, error) {
+	job, err := scheduler.NewJob(gocron.DurationJob(interval), gocron.NewTask(func() error {
+		if err := h.PollTrackingWindows(); err != nil {
+			log.Error("Error polling tracking windows: ", err)
+			return err
+		}
+
+		return nil
+	}), gocron.WithSingletonMode(gocron.LimitModeReschedule))
+
+	return job, err
+}
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subreddit_posts
+ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(selftext, '')), 'B')
+) STORED;
+
+DROP INDEX IF EXISTS idx_subreddit_posts_title;
+CREATE INDEX IF NOT EXISTS idx_subreddit_posts_search_vector ON subreddit_posts USING GIN(search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_subreddit_posts_search_vector;
+CREATE INDEX IF NOT EXISTS idx_subreddit_posts_title ON subreddit_posts(title);
+ALTER TABLE subreddit_posts DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
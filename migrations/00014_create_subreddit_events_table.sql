@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS subreddit_events (
+    id SERIAL PRIMARY KEY,
+    subreddit VARCHAR(32) NOT NULL,
+    bucket_start TIMESTAMP NOT NULL,
+    post_count INT NOT NULL,
+    score_total BIGINT NOT NULL,
+    baseline_count FLOAT NOT NULL,
+    baseline_score FLOAT NOT NULL,
+    count_z FLOAT NOT NULL,
+    score_z FLOAT NOT NULL,
+    top_posts JSONB NOT NULL DEFAULT '[]',
+    trending_terms JSONB NOT NULL DEFAULT '[]',
+    detected_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (subreddit, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subreddit_events_subreddit_bucket ON subreddit_events(subreddit, bucket_start DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS subreddit_events;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS post_terms (
+    post_id VARCHAR(32) NOT NULL REFERENCES subreddit_posts(id) ON DELETE CASCADE,
+    term VARCHAR(128) NOT NULL,
+    count INT NOT NULL,
+    PRIMARY KEY (post_id, term)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_terms_term ON post_terms(term);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS post_terms;
+-- +goose StatementEnd
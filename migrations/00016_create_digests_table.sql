@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS digests (
+    id SERIAL PRIMARY KEY,
+    subreddit VARCHAR(32) NOT NULL,
+    week VARCHAR(8) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    version INT NOT NULL,
+    document JSONB NOT NULL,
+    markdown TEXT NOT NULL,
+    html TEXT NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (subreddit, week)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digests_subreddit_period ON digests(subreddit, period_start DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS digests;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subreddit_posts ADD COLUMN IF NOT EXISTS flair TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE subreddit_posts DROP COLUMN IF EXISTS flair;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE subreddit_posts ADD COLUMN IF NOT EXISTS post_type TEXT NOT NULL DEFAULT 'other';
+CREATE INDEX IF NOT EXISTS idx_subreddit_posts_type_created ON subreddit_posts (subreddit, post_type, created_utc);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_subreddit_posts_type_created;
+ALTER TABLE subreddit_posts DROP COLUMN IF EXISTS post_type;
+-- +goose StatementEnd
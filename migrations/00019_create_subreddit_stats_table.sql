@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS subreddit_stats (
+    subreddit VARCHAR(32) NOT NULL,
+    day DATE NOT NULL,
+    subscribers INT NOT NULL,
+    active_users INT,
+    posts_ingested INT NOT NULL DEFAULT 0,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (subreddit, day)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS subreddit_stats;
+-- +goose StatementEnd
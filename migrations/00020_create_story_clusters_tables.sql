@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS story_clusters (
+    id SERIAL PRIMARY KEY,
+    subreddit VARCHAR(32) NOT NULL,
+    first_post_id VARCHAR(32) NOT NULL,
+    first_author VARCHAR(64) NOT NULL,
+    title TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_story_clusters_subreddit_last_seen ON story_clusters(subreddit, last_seen DESC);
+
+CREATE TABLE IF NOT EXISTS post_signatures (
+    post_id VARCHAR(32) PRIMARY KEY REFERENCES subreddit_posts(id) ON DELETE CASCADE,
+    cluster_id INT NOT NULL REFERENCES story_clusters(id) ON DELETE CASCADE,
+    signature BIGINT[] NOT NULL,
+    bands TEXT[] NOT NULL,
+    similarity FLOAT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_signatures_cluster_id ON post_signatures(cluster_id);
+CREATE INDEX IF NOT EXISTS idx_post_signatures_bands ON post_signatures USING GIN (bands);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS post_signatures;
+DROP TABLE IF EXISTS story_clusters;
+-- +goose StatementEnd
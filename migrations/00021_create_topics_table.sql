@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS topics (
+    id SERIAL PRIMARY KEY,
+    subreddit VARCHAR(32) NOT NULL,
+    month DATE NOT NULL,
+    rank INT NOT NULL,
+    label TEXT NOT NULL,
+    terms JSONB NOT NULL DEFAULT '[]',
+    posts JSONB NOT NULL DEFAULT '[]',
+    post_count INT NOT NULL,
+    share FLOAT NOT NULL,
+    score_total BIGINT NOT NULL,
+    comments_total BIGINT NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (subreddit, month, rank)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS topics;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS watchlists (
+    id SERIAL PRIMARY KEY,
+    reddit_uid VARCHAR(255) NOT NULL,
+    keyword VARCHAR(100) NOT NULL,
+    subreddit VARCHAR(32) NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (reddit_uid) REFERENCES users(reddit_uid) ON DELETE CASCADE,
+    UNIQUE (reddit_uid, keyword, subreddit)
+);
+
+CREATE TABLE IF NOT EXISTS watchlist_matches (
+    watchlist_id INT NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+    post_id VARCHAR(32) NOT NULL REFERENCES subreddit_posts(id) ON DELETE CASCADE,
+    matched_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    read_at timestamp(0) with time zone,
+    PRIMARY KEY (watchlist_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_matches_post_id ON watchlist_matches(post_id);
+CREATE INDEX IF NOT EXISTS idx_watchlist_matches_unread ON watchlist_matches(watchlist_id) WHERE read_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS watchlist_matches;
+DROP TABLE IF EXISTS watchlists;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS box_office_figures (
+    id SERIAL PRIMARY KEY,
+    post_id VARCHAR(32) NOT NULL REFERENCES subreddit_posts(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    crore DOUBLE PRECISION NOT NULL,
+    region VARCHAR(32) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    basis VARCHAR(8) NOT NULL,
+    day INT,
+    raw TEXT NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (post_id, position)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS box_office_figures;
+-- +goose StatementEnd
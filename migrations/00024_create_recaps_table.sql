@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recaps (
+    id SERIAL PRIMARY KEY,
+    subreddit VARCHAR(32) NOT NULL,
+    year INT NOT NULL,
+    complete BOOLEAN NOT NULL DEFAULT FALSE,
+    version INT NOT NULL,
+    document JSONB NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (subreddit, year)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS recaps;
+-- +goose StatementEnd
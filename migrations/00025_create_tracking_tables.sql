@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tracking_windows (
+    id SERIAL PRIMARY KEY,
+    subreddit VARCHAR(32) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    tmdb_id INT,
+    keywords TEXT[] NOT NULL,
+    start_time timestamp(0) with time zone NOT NULL,
+    end_time timestamp(0) with time zone NOT NULL,
+    created_by VARCHAR(255) NOT NULL REFERENCES users(reddit_uid),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracking_windows_period ON tracking_windows(start_time, end_time);
+
+CREATE TABLE IF NOT EXISTS tracking_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    window_id INT NOT NULL REFERENCES tracking_windows(id) ON DELETE CASCADE,
+    post_id VARCHAR(32) NOT NULL,
+    title TEXT NOT NULL,
+    author VARCHAR(64) NOT NULL,
+    permalink VARCHAR(255) NOT NULL,
+    score INT NOT NULL,
+    num_comments INT NOT NULL,
+    upvote_ratio DOUBLE PRECISION NOT NULL,
+    created_utc TIMESTAMP NOT NULL,
+    in_new BOOLEAN NOT NULL,
+    in_hot BOOLEAN NOT NULL,
+    captured_at timestamp(0) with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracking_snapshots_window_post ON tracking_snapshots(window_id, post_id, captured_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tracking_snapshots;
+DROP TABLE IF EXISTS tracking_windows;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS subreddit_term_months (
+    subreddit VARCHAR(32) NOT NULL,
+    month DATE NOT NULL,
+    term VARCHAR(128) NOT NULL,
+    count INT NOT NULL,
+    PRIMARY KEY (subreddit, month, term)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS subreddit_term_months;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS post_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE topics DROP CONSTRAINT IF EXISTS topics_subreddit_month_rank_key;
+ALTER TABLE topics ADD CONSTRAINT topics_subreddit_month_post_type_rank_key UNIQUE (subreddit, month, post_type, rank);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM topics WHERE post_type <> '';
+ALTER TABLE topics DROP CONSTRAINT IF EXISTS topics_subreddit_month_post_type_rank_key;
+ALTER TABLE topics ADD CONSTRAINT topics_subreddit_month_rank_key UNIQUE (subreddit, month, rank);
+ALTER TABLE topics DROP COLUMN IF EXISTS post_type;
+-- +goose StatementEnd
@@ -29,6 +29,8 @@ var (
 	RedditUserAgentWeb string = os.Getenv("REDDIT_USER_AGENT_WEB")
 	JWTSecret          string = os.Getenv("JWT_SECRET")
 	JWTIssuer          string = os.Getenv("JWT_ISSUER")
+	AdminUIDs          string = os.Getenv("ADMIN_REDDIT_UIDS")
+	PublicURL          string = os.Getenv("PUBLIC_URL")
 )
 
 var (
@@ -77,4 +79,12 @@ type Config struct {
 		Burst   int
 		Enabled bool
 	}
+	ScoringProfiles string
+	PostTypeRules   string
+	MovieAliases    string
+	// Admins are the reddit ids of the users who can manage tracking windows.
+	Admins []string
+	// PublicURL is where the api is reached from outside, for the links it
+	// hands out. The Host header can't be trusted for responses that get cached.
+	PublicURL string
 }
@@ -56,6 +56,12 @@ func (u *utilsImpl) UserUnAuthorizedResponse(c echo.Context, err error) {
 	u.resposeError(c, http.StatusUnauthorized, message)
 }
 
+func (u *utilsImpl) ForbiddenResponse(c echo.Context, err error) {
+	message := "You are not allowed to do this"
+	log.Error(err)
+	u.resposeError(c, http.StatusForbidden, message)
+}
+
 func (u *utilsImpl) RateLimitExceededResponse(c echo.Context) {
 	message := "Rate limit exceeded"
 	u.resposeError(c, http.StatusTooManyRequests, message)
@@ -16,6 +16,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -28,6 +29,7 @@ type Utilities interface {
 	ReadFormData(c echo.Context, dst interface{}) error
 	ReadStringQuery(qs url.Values, key string, defaultValue string) string
 	ReadIntQuery(qs url.Values, key string, defaultValue int) int
+	ReadDateQuery(qs url.Values, key string, defaultValue time.Time) (time.Time, error)
 	HandleFiles(c echo.Context, key, name string) ([]string, error)
 	GenerateSignature(orderId, id, secret string) string
 	MakeCustomRequest(httpClient *http.Client, req *http.Request) (map[string]interface{}, error)
@@ -39,6 +41,7 @@ type Utilities interface {
 	NotFoundResponse(c echo.Context)
 	EditConflictResponse(c echo.Context)
 	UserUnAuthorizedResponse(c echo.Context, err error)
+	ForbiddenResponse(c echo.Context, err error)
 	RateLimitExceededResponse(c echo.Context)
 	CustomErrorResponse(c echo.Context, message Cake, status int, err error)
 	ValidationError(c echo.Context, err error)
@@ -181,6 +184,21 @@ func (u *utilsImpl) ReadIntQuery(qs url.Values, key string, defaultValue int) in
 	return res
 }
 
+func (u *utilsImpl) ReadDateQuery(qs url.Values, key string, defaultValue time.Time) (time.Time, error) {
+
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+
+	res, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s date, expected YYYY-MM-DD", key)
+	}
+
+	return res, nil
+}
+
 func (u *utilsImpl) HandleFiles(c echo.Context, key string, name string) ([]string, error) {
 	files := c.Request().MultipartForm.File[key]
 	fmt.Println(files, "\n\nfiles")
@@ -0,0 +1,50 @@
+package wordcloud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+const maxCacheEntries = 64
+
+// Cache keeps rendered clouds by the hash of the word list they were made
+// from, so an unchanged list is never laid out twice.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string][]byte)}
+}
+
+// Hash identifies a word list together with the format it is rendered to.
+func Hash(words []Word, format string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", format)
+	for _, w := range words {
+		fmt.Fprintf(h, "%s\t%d\n", w.Text, w.Count)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.entries[key]
+	return b, ok
+}
+
+func (c *Cache) Set(key string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// word lists only change once a day, so just start over when it fills up
+	if len(c.entries) >= maxCacheEntries {
+		c.entries = make(map[string][]byte)
+	}
+	c.entries[key] = b
+}
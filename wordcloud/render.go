@@ -0,0 +1,123 @@
+package wordcloud
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func (l *Layout) PNG(w io.Writer) error {
+	width := int(float64(l.Width) * l.Scale)
+	height := int(float64(l.Height) * l.Scale)
+
+	background, err := parseHex(l.Background)
+	if err != nil {
+		return err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	for _, p := range l.Words {
+		c, err := parseHex(p.Color)
+		if err != nil {
+			return err
+		}
+
+		mask, err := renderMask(p.Text, p.FontSize*l.Scale)
+		if err != nil {
+			return err
+		}
+
+		if p.Vertical {
+			mask = rotate90(mask)
+		}
+
+		at := image.Pt(int(float64(p.X)*l.Scale), int(float64(p.Y)*l.Scale))
+		draw.DrawMask(img, mask.Bounds().Add(at), image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+	}
+
+	return png.Encode(w, img)
+}
+
+// renderMask draws text horizontally into an alpha mask cropped to its line box.
+func renderMask(text string, size float64) (*image.Alpha, error) {
+	face, err := newFace(size)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, text).Ceil()
+	height := metrics.Ascent.Ceil() + metrics.Descent.Ceil()
+
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	d := font.Drawer{
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  fixed.P(0, metrics.Ascent.Ceil()),
+	}
+	d.DrawString(text)
+
+	return mask, nil
+}
+
+// rotate90 turns the mask counter clockwise, so vertical words read bottom to top.
+func rotate90(src *image.Alpha) *image.Alpha {
+	b := src.Bounds()
+	dst := image.NewAlpha(image.Rect(0, 0, b.Dy(), b.Dx()))
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetAlpha(y, b.Dx()-1-x, src.AlphaAt(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+// SVG writes the layout as text elements. textLength pins every word to the
+// box it was measured into, so the layout holds whatever font the viewer has.
+func (l *Layout) SVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, l.Width, l.Height, l.Width, l.Height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`, l.Background)
+	bw.WriteString(`<g font-family="Go, Helvetica, Arial, sans-serif">`)
+
+	for _, p := range l.Words {
+		_, _, ascent, err := measure(p.Text, p.FontSize)
+		if err != nil {
+			return err
+		}
+
+		length := p.Width
+		x, y := p.X, p.Y+ascent
+		transform := ""
+
+		if p.Vertical {
+			length = p.Height
+			x, y = p.X+ascent, p.Y+p.Height
+			transform = fmt.Sprintf(` transform="rotate(-90 %d %d)"`, x, y)
+		}
+
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-size="%g" fill="%s" textLength="%d" lengthAdjust="spacingAndGlyphs"%s>`, x, y, p.FontSize, p.Color, length, transform)
+		if err := xml.EscapeText(bw, []byte(p.Text)); err != nil {
+			return err
+		}
+		bw.WriteString(`</text>`)
+	}
+
+	bw.WriteString(`</g></svg>`)
+
+	return bw.Flush()
+}
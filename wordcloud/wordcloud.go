@@ -0,0 +1,305 @@
+package wordcloud
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+type Word struct {
+	Text  string
+	Count int
+}
+
+type Options struct {
+	Width            int
+	Height           int
+	Scale            float64
+	MaxWords         int
+	MaxFontSize      float64
+	MinFontSize      float64
+	Background       string
+	Palette          []string
+	PreferHorizontal float64
+	RelativeScaling  float64
+	Margin           int
+	Seed             int64
+}
+
+// DefaultOptions matches the look of the clouds the old python script made.
+var DefaultOptions = Options{
+	Width:            600,
+	Height:           400,
+	Scale:            5,
+	MaxWords:         100,
+	MaxFontSize:      80,
+	MinFontSize:      10,
+	Background:       "#10142C",
+	Palette:          []string{"#f97316", "#0ea5e9", "#eab308", "#ef4444", "#22c55e", "#8b5cf6", "#ec4899"},
+	PreferHorizontal: 0.7,
+	RelativeScaling:  0.5,
+	Margin:           10,
+	Seed:             42,
+}
+
+type Placement struct {
+	Text     string  `json:"text"`
+	Count    int     `json:"count"`
+	X        int     `json:"x"`
+	Y        int     `json:"y"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	FontSize float64 `json:"font_size"`
+	Vertical bool    `json:"vertical"`
+	Color    string  `json:"color"`
+}
+
+type Layout struct {
+	Width      int
+	Height     int
+	Scale      float64
+	Background string
+	Words      []Placement
+}
+
+var goRegular *opentype.Font
+
+func init() {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(fmt.Sprintf("error in parsing word cloud font; %v", err))
+	}
+	goRegular = f
+}
+
+func newFace(size float64) (font.Face, error) {
+	return opentype.NewFace(goRegular, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+}
+
+// measure returns the advance width, height and ascent of text at size.
+func measure(text string, size float64) (int, int, int, error) {
+	face, err := newFace(size)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, text).Ceil()
+	ascent := metrics.Ascent.Ceil()
+
+	return width, ascent + metrics.Descent.Ceil(), ascent, nil
+}
+
+// Generate lays the words out largest first, shrinking a word a point at a
+// time until it fits and stopping once nothing fits at the minimum font size.
+func Generate(words []Word, opts Options) (*Layout, error) {
+	sorted := make([]Word, 0, len(words))
+	for _, w := range words {
+		if w.Count > 0 && w.Text != "" {
+			sorted = append(sorted, w)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Count > sorted[j].Count
+	})
+
+	if len(sorted) > opts.MaxWords {
+		sorted = sorted[:opts.MaxWords]
+	}
+
+	layout := &Layout{
+		Width:      opts.Width,
+		Height:     opts.Height,
+		Scale:      opts.Scale,
+		Background: opts.Background,
+		Words:      []Placement{},
+	}
+
+	if len(sorted) == 0 {
+		return layout, nil
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	grid := newOccupancy(opts.Width, opts.Height)
+
+	maxCount := float64(sorted[0].Count)
+	lastFreq := 1.0
+	fontSize := opts.MaxFontSize
+
+	for _, w := range sorted {
+		freq := float64(w.Count) / maxCount
+
+		if opts.RelativeScaling != 0 {
+			fontSize = math.Round((opts.RelativeScaling*(freq/lastFreq) + (1 - opts.RelativeScaling)) * fontSize)
+		}
+
+		vertical := rng.Float64() > opts.PreferHorizontal
+
+		var placed bool
+		for ; fontSize >= opts.MinFontSize; fontSize-- {
+			mask, err := renderMask(w.Text, fontSize)
+			if err != nil {
+				return nil, err
+			}
+
+			if vertical {
+				mask = rotate90(mask)
+			}
+
+			boxW, boxH := mask.Bounds().Dx()+opts.Margin, mask.Bounds().Dy()+opts.Margin
+
+			x, y, ok := grid.find(boxW, boxH, rng)
+			if !ok {
+				continue
+			}
+
+			grid.fill(mask, x+opts.Margin/2, y+opts.Margin/2)
+
+			layout.Words = append(layout.Words, Placement{
+				Text:     w.Text,
+				Count:    w.Count,
+				X:        x + opts.Margin/2,
+				Y:        y + opts.Margin/2,
+				Width:    boxW - opts.Margin,
+				Height:   boxH - opts.Margin,
+				FontSize: fontSize,
+				Vertical: vertical,
+				Color:    opts.Palette[rng.Intn(len(opts.Palette))],
+			})
+			placed = true
+			break
+		}
+
+		if !placed {
+			break
+		}
+
+		lastFreq = freq
+	}
+
+	return layout, nil
+}
+
+// occupancy keeps the inked pixels of the canvas with a summed area table, so
+// checking whether a box is free doesn't have to walk over its pixels. Only
+// glyph pixels are taken, which lets smaller words settle into the gaps.
+type occupancy struct {
+	width, height int
+	taken         []bool
+	sums          []int
+}
+
+func newOccupancy(width, height int) *occupancy {
+	return &occupancy{
+		width:  width,
+		height: height,
+		taken:  make([]bool, width*height),
+		sums:   make([]int, (width+1)*(height+1)),
+	}
+}
+
+func (o *occupancy) recompute() {
+	stride := o.width + 1
+	for y := 1; y <= o.height; y++ {
+		row := 0
+		for x := 1; x <= o.width; x++ {
+			if o.taken[(y-1)*o.width+x-1] {
+				row++
+			}
+			o.sums[y*stride+x] = o.sums[(y-1)*stride+x] + row
+		}
+	}
+}
+
+func (o *occupancy) free(x, y, w, h int) bool {
+	stride := o.width + 1
+	total := o.sums[(y+h)*stride+x+w] - o.sums[y*stride+x+w] - o.sums[(y+h)*stride+x] + o.sums[y*stride+x]
+	return total == 0
+}
+
+func (o *occupancy) fill(mask *image.Alpha, x, y int) {
+	b := mask.Bounds()
+	for row := 0; row < b.Dy(); row++ {
+		for col := 0; col < b.Dx(); col++ {
+			if mask.AlphaAt(b.Min.X+col, b.Min.Y+row).A == 0 {
+				continue
+			}
+			if px, py := x+col, y+row; px < o.width && py < o.height {
+				o.taken[py*o.width+px] = true
+			}
+		}
+	}
+	o.recompute()
+}
+
+// find walks an archimedean spiral out from a random point near the centre
+// and returns the first free top left corner for a w by h box. When the
+// spiral steps over every gap it falls back to a random free corner.
+func (o *occupancy) find(w, h int, rng *rand.Rand) (int, int, bool) {
+	if w > o.width || h > o.height {
+		return 0, 0, false
+	}
+
+	maxX, maxY := o.width-w, o.height-h
+	cx := float64(maxX)/2 + (rng.Float64()-0.5)*float64(maxX)/4
+	cy := float64(maxY)/2 + (rng.Float64()-0.5)*float64(maxY)/4
+
+	maxRadius := math.Hypot(float64(o.width), float64(o.height))
+	ratio := float64(o.width) / float64(o.height)
+
+	for t := 0.0; t*2 < maxRadius; t += 0.1 {
+		x := int(math.Round(cx + ratio*t*math.Cos(t)))
+		y := int(math.Round(cy + t*math.Sin(t)))
+
+		if x < 0 || y < 0 || x > maxX || y > maxY {
+			continue
+		}
+
+		if o.free(x, y, w, h) {
+			return x, y, true
+		}
+	}
+
+	var corners []image.Point
+	for y := 0; y <= maxY; y += 2 {
+		for x := 0; x <= maxX; x += 2 {
+			if o.free(x, y, w, h) {
+				corners = append(corners, image.Pt(x, y))
+			}
+		}
+	}
+
+	if len(corners) == 0 {
+		return 0, 0, false
+	}
+
+	p := corners[rng.Intn(len(corners))]
+	return p.X, p.Y, true
+}
+
+func parseHex(hex string) (color.RGBA, error) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid colour %s", hex)
+	}
+
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %s", hex)
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}